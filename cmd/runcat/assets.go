@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// assets子命令的用法
+const assetsUsage = `usage: runcat assets <command>
+
+commands:
+  verify   decode every frame of the built-in and custom runners and report problems`
+
+// runAssets 处理 runcat assets 子命令
+func runAssets(args []string) error {
+	if len(args) == 0 {
+		return errors.New(assetsUsage)
+	}
+	switch args[0] {
+	case "verify":
+		return runAssetsVerify()
+	default:
+		return fmt.Errorf("unknown assets command %q\n%s", args[0], assetsUsage)
+	}
+}
+
+// runAssetsVerify 校验内置和自定义角色的所有帧
+func runAssetsVerify() error {
+	rm, _, err := loadUserRunners()
+	if err != nil {
+		return err
+	}
+	if err := rm.Verify(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Verified %d runners\n", len(rm.Runners()))
+	return nil
+}
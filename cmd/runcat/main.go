@@ -1,34 +1,127 @@
 package main
 
 import (
-	"embed"
+	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/eatmoreapple/go-runcat"
 	. "github.com/eatmoreapple/go-runcat/internal/app"
+	"github.com/eatmoreapple/go-runcat/internal/config"
+	"github.com/eatmoreapple/go-runcat/internal/tui"
 )
 
-//go:embed assets
-var assets embed.FS
+// 内置角色的动画帧
+var assets = runcat.Assets
+
+// 关闭应用程序的最长等待时间
+const shutdownTimeout = 5 * time.Second
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "runner":
+			if err := runRunner(os.Args[2:]); err != nil {
+				log.Println("Failed to run runner command:", err)
+				os.Exit(1)
+			}
+			return
+		case "assets":
+			if err := runAssets(os.Args[2:]); err != nil {
+				log.Println("Failed to run assets command:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	tuiEnabled := flag.Bool("tui", false, "render the runner in the terminal instead of the system tray")
+	tuiMode := flag.String("tui-mode", string(tui.ModeAuto), "terminal rendering: auto, unicode, ascii or kitty")
+	configPath := flag.String("config", "", "path to the config file (default: <user config dir>/go-runcat/config.yaml)")
+	flag.String("runner", "", "runner to use, overrides the config file and "+config.EnvPrefix+"_RUNNER")
+	flag.String("theme", "", "theme to use: auto, light or dark, overrides the config file and "+config.EnvPrefix+"_THEME")
+	flag.String("speed-limit", "", "speed limit, e.g. default or cpu10, overrides the config file and "+config.EnvPrefix+"_SPEED_LIMIT")
+	profile := flag.String("profile", "", "switch to a profile defined in the config file, overrides "+config.EnvPrefix+"_PROFILE")
+	flag.String("interval", "", "sampling interval, e.g. 1s, overrides the config file and "+config.EnvPrefix+"_INTERVAL")
+	flag.Parse()
+
+	opts := config.Options{
+		Path:      *configPath,
+		Profile:   *profile,
+		Overrides: configOverrides(),
+	}
+
+	// 终端模式
+	if *tuiEnabled {
+		if err := runTUI(*tuiMode, opts); err != nil {
+			log.Println("Failed to run terminal mode:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 创建应用程序实例
-	app, err := NewApp(assets)
+	app, err := NewApp(assets, opts)
 	if err != nil {
 		log.Println("Failed to create application:", err)
 		os.Exit(1)
 	}
 
-	// 运行应用程序
+	// 在进入系统托盘循环之前处理信号
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.Println("Received signal:", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := app.Shutdown(ctx); err != nil {
+			log.Println("Failed to shut down application:", err)
+			os.Exit(1)
+		}
+	}()
+
+	// 运行应用程序，直到退出
 	if err = app.Run(); err != nil {
 		log.Println("Failed to run application:", err)
 		os.Exit(1)
 	}
+}
 
-	// 等待信号
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+// 命令行选项对应的配置项
+var overrideFlags = map[string]string{
+	"runner":      "runner",
+	"theme":       "theme",
+	"speed-limit": "speed_limit",
+	"interval":    "interval",
+}
+
+// configOverrides 返回命令行中明确指定的配置项，未指定的选项不覆盖环境变量和配置文件
+func configOverrides() map[string]string {
+	overrides := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		if key, ok := overrideFlags[f.Name]; ok {
+			overrides[key] = f.Value.String()
+		}
+	})
+	return overrides
+}
+
+// runTUI 在终端中运行动画，直到收到退出信号
+func runTUI(modeFlag string, opts config.Options) error {
+	mode, err := tui.ParseMode(modeFlag)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return RunTUI(ctx, assets, os.Stdout, mode, opts)
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+)
+
+// runner子命令的用法
+const runnerUsage = `usage: runcat runner <command> [arguments]
+
+commands:
+  import [flags] <file>    convert an animated GIF/APNG or a PNG sprite sheet into a runner
+  install [flags] <file>   install a .runcat runner pack
+  list                     list installed custom runners
+  remove <name>...         remove installed custom runners`
+
+// runRunner 处理 runcat runner 子命令
+func runRunner(args []string) error {
+	if len(args) == 0 {
+		return errors.New(runnerUsage)
+	}
+	switch args[0] {
+	case "import":
+		return runRunnerImport(args[1:])
+	case "install":
+		return runRunnerInstall(args[1:])
+	case "list":
+		return runRunnerList()
+	case "remove":
+		return runRunnerRemove(args[1:])
+	default:
+		return fmt.Errorf("unknown runner command %q\n%s", args[0], runnerUsage)
+	}
+}
+
+// runRunnerImport 将动画或精灵图导入为自定义角色
+func runRunnerImport(args []string) error {
+	fs := flag.NewFlagSet("runner import", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: runcat runner import [flags] <file.gif|file.png>")
+		fs.PrintDefaults()
+	}
+	out := fs.String("out", "", "runner directory to create (default: <config dir>/go-runcat/runners/<file name>)")
+	name := fs.String("name", "", "display name in the Runner menu (default: directory name)")
+	author := fs.String("author", "", "author written to runner.yaml")
+	columns := fs.Int("columns", 0, "sprite sheet columns (default: inferred from square frames)")
+	rows := fs.Int("rows", 0, "sprite sheet rows (default: inferred from square frames)")
+	frames := fs.Int("frames", 0, "number of frames when the last sprite sheet row is not full")
+	size := fs.Int("size", resource.DefaultFrameSize, fmt.Sprintf("edge length of the generated PNG frames (1-%d)", resource.MaxFrameSize))
+	dark := fs.String("dark", string(resource.DarkInvert), "dark variant: invert, recolor or copy")
+	force := fs.Bool("force", false, "overwrite an existing runner directory")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("runner import: expected exactly one input file")
+	}
+	if *size < 1 || *size > resource.MaxFrameSize {
+		fs.Usage()
+		return fmt.Errorf("runner import: -size must be between 1 and %d", resource.MaxFrameSize)
+	}
+
+	darkMode, err := resource.ParseDarkMode(*dark)
+	if err != nil {
+		return err
+	}
+
+	input := fs.Arg(0)
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	dir := *out
+	if dir == "" {
+		runnersDir, err := resource.UserRunnersDir()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(runnersDir, strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)))
+	}
+
+	manifest, err := resource.ImportRunner(data, dir, resource.ImportOptions{
+		Name:   *name,
+		Author: *author,
+		Layout: resource.SpriteLayout{Columns: *columns, Rows: *rows, Frames: *frames},
+		Size:   *size,
+		Dark:   darkMode,
+		Force:  *force,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %s with %d frames into %s\n", manifest.Name, len(manifest.Frames), dir)
+	return nil
+}
+
+// 创建资源管理器并加载自定义角色目录
+func loadUserRunners() (*resource.Manager, string, error) {
+	dir, err := resource.UserRunnersDir()
+	if err != nil {
+		return nil, "", err
+	}
+	rm := resource.NewResourceManager(assets)
+	if err := rm.LoadUserRunners(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, "", err
+	}
+	return rm, dir, nil
+}
+
+// runRunnerInstall 安装角色包到自定义角色目录
+func runRunnerInstall(args []string) error {
+	fs := flag.NewFlagSet("runner install", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: runcat runner install [flags] <file%s>\n", resource.PackExtension)
+		fs.PrintDefaults()
+	}
+	name := fs.String("name", "", "runner name (default: pack file name)")
+	force := fs.Bool("force", false, "replace an installed runner with the same name")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("runner install: expected exactly one pack file")
+	}
+
+	input := fs.Arg(0)
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	runner := resource.RunnerType(*name)
+	if runner == "" {
+		runner = resource.RunnerType(strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)))
+	}
+
+	rm, dir, err := loadUserRunners()
+	if err != nil {
+		return err
+	}
+	info, err := rm.InstallPack(file, stat.Size(), runner, *force)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Installed %s as %s into %s\n", info.Label, runner, filepath.Join(dir, string(runner)))
+	return nil
+}
+
+// runRunnerList 列出已安装的自定义角色
+func runRunnerList() error {
+	rm, dir, err := loadUserRunners()
+	if err != nil {
+		return err
+	}
+
+	runners := rm.InstalledRunners()
+	if len(runners) == 0 {
+		fmt.Printf("No custom runners installed in %s\n", dir)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tLABEL\tAUTHOR\tFRAMES")
+	for _, runner := range runners {
+		info := rm.RunnerInfo(runner)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", runner, info.Label, info.Author, rm.GetIconCount(runner, theme.LightType))
+	}
+	return w.Flush()
+}
+
+// runRunnerRemove 删除已安装的自定义角色
+func runRunnerRemove(args []string) error {
+	if len(args) == 0 {
+		return errors.New("runner remove: expected at least one runner name")
+	}
+
+	rm, _, err := loadUserRunners()
+	if err != nil {
+		return err
+	}
+	for _, name := range args {
+		if err := rm.RemoveRunner(resource.RunnerType(name)); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// Package runcat 提供内置的资源，不依赖系统托盘的cgo实现，可以在任何平台上构建和测试
+package runcat
+
+import "embed"
+
+// Assets 内置角色的动画帧，位于 assets/<角色>/<主题>/ 目录
+//
+//go:embed assets
+var Assets embed.FS
@@ -0,0 +1,13 @@
+package runcat
+
+import (
+	"testing"
+
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+)
+
+func TestEmbeddedAssets(t *testing.T) {
+	if err := resource.NewResourceManager(Assets).Verify(); err != nil {
+		t.Fatalf("embedded assets are invalid:\n%v", err)
+	}
+}
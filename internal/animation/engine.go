@@ -0,0 +1,158 @@
+package animation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eatmoreapple/go-runcat/internal/loop"
+)
+
+// Sink 接收动画帧的输出，例如系统托盘图标或终端
+type Sink interface {
+	// ShowFrame 显示一帧，调用是串行的
+	ShowFrame(frame []byte)
+}
+
+// SinkFunc 将普通函数适配为Sink
+type SinkFunc func(frame []byte)
+
+// ShowFrame 调用f(frame)
+func (f SinkFunc) ShowFrame(frame []byte) {
+	f(frame)
+}
+
+// Engine 按速度信号逐帧播放动画，并将帧输出到Sink
+type Engine struct {
+	// 帧输出
+	sink Sink
+	// 动画循环
+	loop loop.Loop
+	// 保证帧按顺序输出
+	emitMu sync.Mutex
+
+	// 保护以下字段的互斥锁
+	mu sync.Mutex
+	// 动画帧
+	frames [][]byte
+	// 当前帧索引
+	index int
+	// 驱动速度的指标值 (0-100)
+	usage float64
+	// 动画节奏
+	timing Timing
+}
+
+// NewEngine 创建一个新的动画引擎
+func NewEngine(sink Sink) *Engine {
+	return &Engine{sink: sink}
+}
+
+// SetFrames 替换动画帧并立即输出当前帧，帧数变少时从第一帧继续
+func (e *Engine) SetFrames(frames [][]byte) {
+	e.emitMu.Lock()
+	defer e.emitMu.Unlock()
+
+	e.mu.Lock()
+	e.frames = frames
+	if e.index >= len(frames) {
+		e.index = 0
+	}
+	frame, ok := e.current()
+	e.mu.Unlock()
+
+	if ok {
+		e.sink.ShowFrame(frame)
+	}
+}
+
+// Rewind 下一次SetFrames时从第一帧开始播放
+func (e *Engine) Rewind() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.index = 0
+}
+
+// SetUsage 设置驱动速度的指标值 (0-100)，值越大动画越快
+func (e *Engine) SetUsage(usage float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.usage = usage
+}
+
+// SetTiming 设置每帧的相对时长和速度范围
+func (e *Engine) SetTiming(timing Timing) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.timing = timing
+}
+
+// Interval 返回当前帧的显示时长
+func (e *Engine) Interval() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.timing.Interval(e.usage, e.index)
+}
+
+// Index 返回当前帧索引
+func (e *Engine) Index() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.index
+}
+
+// Start 开始播放动画，直到ctx取消或调用Stop；已在播放时不做任何操作
+func (e *Engine) Start(ctx context.Context) {
+	e.loop.Start(ctx, e.run)
+}
+
+// Stop 停止播放并等待动画循环退出
+func (e *Engine) Stop() {
+	e.loop.Stop()
+}
+
+// IsRunning 检查动画是否正在播放
+func (e *Engine) IsRunning() bool {
+	return e.loop.IsRunning()
+}
+
+// 动画循环
+func (e *Engine) run(ctx context.Context) {
+	timer := time.NewTimer(e.Interval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			e.step()
+			timer.Reset(e.Interval())
+		}
+	}
+}
+
+// 切换到下一帧并输出
+func (e *Engine) step() {
+	e.emitMu.Lock()
+	defer e.emitMu.Unlock()
+
+	e.mu.Lock()
+	if len(e.frames) > 0 {
+		e.index = (e.index + 1) % len(e.frames)
+	}
+	frame, ok := e.current()
+	e.mu.Unlock()
+
+	if ok {
+		e.sink.ShowFrame(frame)
+	}
+}
+
+// 当前帧，调用时需持有锁
+func (e *Engine) current() ([]byte, bool) {
+	if len(e.frames) == 0 {
+		return nil, false
+	}
+	return e.frames[e.index], true
+}
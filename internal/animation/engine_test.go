@@ -0,0 +1,235 @@
+package animation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink 记录输出的每一帧
+type fakeSink struct {
+	mu     sync.Mutex
+	frames []string
+	notify chan struct{}
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{notify: make(chan struct{}, 1024)}
+}
+
+func (s *fakeSink) ShowFrame(frame []byte) {
+	s.mu.Lock()
+	s.frames = append(s.frames, string(frame))
+	s.mu.Unlock()
+	s.notify <- struct{}{}
+}
+
+func (s *fakeSink) shown() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.frames...)
+}
+
+// 等待至少n帧
+func (s *fakeSink) waitFrames(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		if frames := s.shown(); len(frames) >= n {
+			return frames
+		}
+		select {
+		case <-s.notify:
+		case <-deadline:
+			t.Fatalf("got %d frames, want at least %d", len(s.shown()), n)
+		}
+	}
+}
+
+func frames(names ...string) [][]byte {
+	out := make([][]byte, len(names))
+	for i, name := range names {
+		out[i] = []byte(name)
+	}
+	return out
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		usage float64
+		want  time.Duration
+	}{
+		{0, 200 * time.Millisecond},
+		{5, 200 * time.Millisecond},
+		{10, 100 * time.Millisecond},
+		{20, 50 * time.Millisecond},
+		{30, 33 * time.Millisecond},
+		{40, 25 * time.Millisecond},
+		{100, 10 * time.Millisecond},
+		{150, 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := Interval(tt.usage); got != tt.want {
+			t.Errorf("Interval(%v) = %v, want %v", tt.usage, got, tt.want)
+		}
+	}
+}
+
+func TestTimingInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		timing Timing
+		usage  float64
+		frame  int
+		want   time.Duration
+	}{
+		{"default idle", Timing{}, 0, 0, 200 * time.Millisecond},
+		{"relative durations", Timing{Durations: []float64{1, 1, 2}}, 20, 2, 75 * time.Millisecond},
+		{"relative durations short frame", Timing{Durations: []float64{1, 1, 2}}, 20, 0, 37 * time.Millisecond},
+		{"scaled durations are equal", Timing{Durations: []float64{3, 3}}, 20, 1, 50 * time.Millisecond},
+		{"frame beyond durations", Timing{Durations: []float64{1, 2}}, 20, 5, 50 * time.Millisecond},
+		{"min speed", Timing{MinSpeed: 2}, 0, 0, 100 * time.Millisecond},
+		{"max speed", Timing{MaxSpeed: 4}, 100, 0, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := tt.timing.Interval(tt.usage, tt.frame); got != tt.want {
+			t.Errorf("%s: Interval(%v, %d) = %v, want %v", tt.name, tt.usage, tt.frame, got, tt.want)
+		}
+	}
+}
+
+func TestEngineCyclesFrames(t *testing.T) {
+	sink := newFakeSink()
+	e := NewEngine(sink)
+	e.SetUsage(100)
+	e.SetFrames(frames("a", "b", "c"))
+
+	e.Start(context.Background())
+	got := sink.waitFrames(t, 7)
+	e.Stop()
+
+	want := []string{"a", "b", "c", "a", "b", "c", "a"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("frame sequence %v, want prefix %v", got, want)
+		}
+	}
+}
+
+func TestEngineSetFrames(t *testing.T) {
+	sink := newFakeSink()
+	e := NewEngine(sink)
+
+	// 没有帧时不输出
+	e.step()
+	if n := len(sink.shown()); n != 0 {
+		t.Fatalf("shown %d frames without frames set", n)
+	}
+
+	e.SetFrames(frames("a", "b", "c"))
+	e.step()
+	e.step()
+	if e.Index() != 2 {
+		t.Fatalf("index %d, want 2", e.Index())
+	}
+
+	// 替换为同样数量的帧时保持位置（例如切换主题）
+	e.SetFrames(frames("A", "B", "C"))
+	if got := sink.shown(); got[len(got)-1] != "C" {
+		t.Fatalf("after SetFrames showed %q, want %q", got[len(got)-1], "C")
+	}
+
+	// 帧数变少时从第一帧继续
+	e.SetFrames(frames("x", "y"))
+	if e.Index() != 0 {
+		t.Fatalf("index %d after shrinking frames, want 0", e.Index())
+	}
+
+	// Rewind后从第一帧开始（例如切换角色）
+	e.step()
+	e.Rewind()
+	e.SetFrames(frames("p", "q", "r"))
+	if got := sink.shown(); got[len(got)-1] != "p" {
+		t.Fatalf("after Rewind showed %q, want %q", got[len(got)-1], "p")
+	}
+}
+
+func TestEngineUsageControlsSpeed(t *testing.T) {
+	sink := newFakeSink()
+	e := NewEngine(sink)
+	e.SetFrames(frames("a", "b"))
+
+	e.SetUsage(0)
+	if got := e.Interval(); got != 200*time.Millisecond {
+		t.Fatalf("idle interval %v, want 200ms", got)
+	}
+	e.SetUsage(100)
+	if got := e.Interval(); got != 10*time.Millisecond {
+		t.Fatalf("busy interval %v, want 10ms", got)
+	}
+}
+
+func TestEngineStartStopRestart(t *testing.T) {
+	sink := newFakeSink()
+	e := NewEngine(sink)
+	e.SetUsage(100)
+	e.SetFrames(frames("a", "b"))
+
+	for i := 0; i < 3; i++ {
+		before := len(sink.shown())
+		e.Start(context.Background())
+		if !e.IsRunning() {
+			t.Fatalf("round %d: IsRunning false after Start", i)
+		}
+		sink.waitFrames(t, before+2)
+		e.Stop()
+		if e.IsRunning() {
+			t.Fatalf("round %d: IsRunning true after Stop", i)
+		}
+
+		// 停止后不再输出
+		stopped := len(sink.shown())
+		time.Sleep(30 * time.Millisecond)
+		if n := len(sink.shown()); n != stopped {
+			t.Fatalf("round %d: %d frames shown after Stop", i, n-stopped)
+		}
+	}
+}
+
+func TestEngineConcurrentUpdates(t *testing.T) {
+	sink := newFakeSink()
+	e := NewEngine(sink)
+	e.SetUsage(100)
+	e.SetFrames(frames("a", "b", "c"))
+	e.Start(context.Background())
+	defer e.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			e.SetUsage(float64(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if i%2 == 0 {
+				e.SetFrames(frames("a", "b", "c"))
+			} else {
+				e.Rewind()
+				e.SetFrames(frames("x"))
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = e.Index()
+			_ = e.Interval()
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,49 @@
+package animation
+
+import "time"
+
+const (
+	// MinSpeed 默认的最低速度倍数，对应200ms的帧间隔
+	MinSpeed = 1.0
+	// MaxSpeed 默认的最高速度倍数，对应10ms的帧间隔
+	MaxSpeed = 20.0
+)
+
+// Interval 根据归一化的指标值 (0-100) 计算帧间隔
+// 使用与原始RunCat相同的算法：200ms / clamp(usage/5, 1, 20)
+func Interval(usage float64) time.Duration {
+	return Timing{}.Interval(usage, 0)
+}
+
+// Timing 角色的动画节奏
+type Timing struct {
+	// 每帧的相对时长，为空时每帧相同；按平均值归一化，不改变整体速度
+	Durations []float64
+	// 最低速度倍数，为0时使用MinSpeed
+	MinSpeed float64
+	// 最高速度倍数，为0时使用MaxSpeed
+	MaxSpeed float64
+}
+
+// Interval 根据归一化的指标值 (0-100) 计算第frame帧的显示时长
+func (t Timing) Interval(usage float64, frame int) time.Duration {
+	lo, hi := MinSpeed, MaxSpeed
+	if t.MinSpeed > 0 {
+		lo = t.MinSpeed
+	}
+	if t.MaxSpeed > 0 {
+		hi = t.MaxSpeed
+	}
+	interval := 200.0 / max(lo, min(hi, usage/5.0))
+
+	if frame >= 0 && frame < len(t.Durations) {
+		var total float64
+		for _, d := range t.Durations {
+			total += d
+		}
+		if total > 0 && t.Durations[frame] > 0 {
+			interval *= t.Durations[frame] * float64(len(t.Durations)) / total
+		}
+	}
+	return time.Duration(interval) * time.Millisecond
+}
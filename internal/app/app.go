@@ -1,20 +1,28 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"io/fs"
-	"time"
+	"log"
+	"slices"
+	"strings"
+	"sync"
 
+	"github.com/eatmoreapple/go-runcat/internal/config"
 	"github.com/eatmoreapple/go-runcat/internal/monitor"
+	"github.com/eatmoreapple/go-runcat/internal/overlay"
 	"github.com/eatmoreapple/go-runcat/internal/platform"
 	"github.com/eatmoreapple/go-runcat/internal/resource"
 	"github.com/eatmoreapple/go-runcat/internal/systray"
+	"github.com/eatmoreapple/go-runcat/internal/systray/lantern"
 	"github.com/eatmoreapple/go-runcat/internal/theme"
 )
 
 // App 应用程序
 type App struct {
 	// 配置管理器
-	configManager *ConfigManager
+	configManager *config.Manager
 	// 平台实现
 	platform platform.Platform
 	// 主题管理器
@@ -23,12 +31,23 @@ type App struct {
 	systrayManager *systray.Manager
 	// CPU监控器
 	cpuMonitor *monitor.CPUMonitor
+
+	// 应用程序运行的context，关闭时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+	// 确保关闭流程只执行一次
+	shutdownOnce sync.Once
+	// 关闭流程的结果
+	shutdownErr error
 }
 
-// NewApp 创建一个新的应用程序实例
-func NewApp(fs fs.FS) (*App, error) {
+// NewApp 创建一个新的应用程序实例，opts为启动时指定的配置选项
+func NewApp(fs fs.FS, opts config.Options) (*App, error) {
+	// 创建资源管理器
+	rm := newResourceManager(fs)
+
 	// 创建配置管理器
-	configManager, err := NewConfigManager()
+	configManager, err := config.NewManager(rm.Runners(), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -36,9 +55,6 @@ func NewApp(fs fs.FS) (*App, error) {
 	// 创建平台实现
 	p := platform.NewPlatform()
 
-	// 创建资源管理器
-	rm := resource.NewResourceManager(fs)
-
 	// 创建主题管理器
 	tm := theme.NewManager(p)
 
@@ -47,32 +63,222 @@ func NewApp(fs fs.FS) (*App, error) {
 	tm.SetTheme(theme.Type(config.Theme))
 
 	// 创建系统托盘管理器
-	sm := systray.NewSystrayManager(p, rm, tm)
+	sm := systray.NewSystrayManager(lantern.NewBackend(), p, rm, tm, traySettings(config))
 
 	// 创建CPU监控器
-	cm := monitor.NewCPUMonitor(3 * time.Second)
+	cm := newCPUMonitor(config)
 
-	return &App{
+	ctx, cancel := context.WithCancel(context.Background())
+	app := &App{
 		configManager:  configManager,
 		platform:       p,
 		themeManager:   tm,
 		systrayManager: sm,
 		cpuMonitor:     cm,
-	}, nil
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	// 菜单修改的设置写回配置文件
+	sm.SetOnSettingsChanged(app.saveSettings)
+	// 菜单选择的配置方案
+	sm.SetOnProfileSelected(app.selectProfile)
+	// 菜单创建完成后才应用手动修改的配置文件，关闭时停止监听
+	sm.SetOnReady(func() {
+		configManager.Watch(ctx, app.reloadConfig)
+	})
+
+	return app, nil
 }
 
-// Run 运行应用程序
+// Run 运行应用程序，阻塞直到系统托盘退出
+// 通过菜单退出时同样执行关闭流程
 func (a *App) Run() error {
-	// 设置CPU使用率更新回调
-	a.cpuMonitor.OnUpdate = func(usage float64) {
-		a.systrayManager.SetCPUUsage(usage)
+	// 跟随系统主题变化
+	go a.watchSystemTheme(a.ctx)
+
+	// 设置监控数据更新回调
+	a.cpuMonitor.OnUpdate = func(sample monitor.Sample) {
+		a.systrayManager.SetUsage(sample)
 	}
 
 	// 启动CPU监控
-	a.cpuMonitor.Start()
+	a.cpuMonitor.Start(a.ctx)
 
 	// 启动系统托盘
 	a.systrayManager.Start()
 
-	return nil
+	return a.shutdown()
+}
+
+// Shutdown 停止监控和动画，写入尚未保存的配置并退出系统托盘
+// ctx到期时不再等待，返回ctx的错误；可以多次调用
+func (a *App) Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- a.shutdown()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 关闭流程
+func (a *App) shutdown() error {
+	a.shutdownOnce.Do(func() {
+		// 停止主题和配置文件监听等后台任务
+		a.cancel()
+
+		// 停止CPU监控
+		a.cpuMonitor.Stop()
+
+		// 停止动画并退出系统托盘
+		a.systrayManager.Quit()
+
+		// 写入尚未保存的配置修改，没有修改时不改动配置文件
+		a.shutdownErr = a.configManager.Save()
+	})
+	return a.shutdownErr
+}
+
+// watchSystemTheme 监听系统主题变化并更新自动主题
+func (a *App) watchSystemTheme(ctx context.Context) {
+	for range platform.WatchSystemTheme(ctx, a.platform, platform.DefaultThemePollInterval) {
+		a.themeManager.UpdateSystemTheme()
+	}
+}
+
+// saveSettings 应用并保存托盘菜单修改的设置
+func (a *App) saveSettings(settings systray.Settings) {
+	config := a.configManager.GetConfig()
+
+	// 切换监控指标
+	if settings.Metric != config.Metric || settings.DriveExpression != config.DriveExpression {
+		source, err := newMonitorSource(settings.Metric, settings.DriveExpression)
+		if err != nil {
+			log.Printf("Failed to create monitor source: %v", err)
+		} else {
+			a.cpuMonitor.SetSource(source)
+		}
+	}
+
+	// 修改采样间隔
+	if settings.Interval != config.Interval {
+		a.cpuMonitor.SetInterval(settings.Interval)
+	}
+
+	config.Runner = string(settings.Runner)
+	config.Theme = string(settings.Theme)
+	config.SpeedLimit = string(settings.SpeedLimit)
+	config.Metric = settings.Metric
+	config.DriveExpression = settings.DriveExpression
+	config.Interval = settings.Interval
+	config.Overlay = string(settings.Overlay)
+	config.Profile = settings.Profile
+	a.configManager.SetConfig(config)
+}
+
+// selectProfile 切换到托盘菜单选择的配置方案
+func (a *App) selectProfile(name string) {
+	old := a.configManager.GetConfig()
+	if err := a.configManager.SelectProfile(name); err != nil {
+		log.Printf("Failed to switch profile: %v", err)
+		return
+	}
+	log.Printf("Switched to profile %s", name)
+	a.applyConfig(old, a.configManager.GetConfig())
+}
+
+// reloadConfig 应用手动修改后重新加载的配置
+func (a *App) reloadConfig(old, current config.Config) {
+	log.Printf("Config reloaded, changed: %s", strings.Join(config.ChangedFields(old, current), ", "))
+	a.applyConfig(old, current)
+}
+
+// applyConfig 将变化的配置应用到监控器和系统托盘
+func (a *App) applyConfig(old, config config.Config) {
+	// 切换监控指标
+	if config.Metric != old.Metric || config.DriveExpression != old.DriveExpression {
+		source, err := newMonitorSource(config.Metric, config.DriveExpression)
+		if err != nil {
+			log.Printf("Failed to create monitor source: %v", err)
+		} else {
+			a.cpuMonitor.SetSource(source)
+		}
+	}
+
+	// 修改采样间隔
+	if config.Interval != old.Interval {
+		a.cpuMonitor.SetInterval(config.Interval)
+	}
+
+	// 修改过滤器
+	if !slices.Equal(config.Filters, old.Filters) {
+		if filters, err := monitor.ParseFilters(config.Filters); err == nil {
+			a.cpuMonitor.SetFilters(filters...)
+		} else {
+			log.Printf("Failed to create monitor filters: %v", err)
+		}
+	}
+
+	// 角色、主题等设置交给系统托盘，同时更新菜单的选中状态
+	a.systrayManager.ApplySettings(traySettings(config))
+}
+
+// traySettings 从配置中提取系统托盘的设置
+func traySettings(config config.Config) systray.Settings {
+	return systray.Settings{
+		Runner:          resource.RunnerType(config.Runner),
+		Theme:           theme.Type(config.Theme),
+		SpeedLimit:      systray.SpeedLimitType(config.SpeedLimit),
+		Metric:          config.Metric,
+		DriveExpression: config.DriveExpression,
+		Interval:        config.Interval,
+		Overlay:         overlay.Mode(config.Overlay),
+		Profile:         config.Profile,
+		Profiles:        config.ProfileNames(),
+	}
+}
+
+// newResourceManager 创建资源管理器，并加载用户目录中的自定义角色
+func newResourceManager(assets fs.FS) *resource.Manager {
+	rm := resource.NewResourceManager(assets)
+
+	dir, err := resource.UserRunnersDir()
+	if err != nil {
+		log.Printf("Failed to locate custom runners: %v", err)
+		return rm
+	}
+	if err := rm.LoadUserRunners(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Failed to load custom runners from %s: %v", dir, err)
+	}
+	return rm
+}
+
+// newCPUMonitor 根据配置创建监控器
+func newCPUMonitor(config config.Config) *monitor.CPUMonitor {
+	cm := monitor.NewCPUMonitor(config.Interval)
+	if source, err := newMonitorSource(config.Metric, config.DriveExpression); err == nil {
+		cm.SetSource(source)
+	} else {
+		log.Printf("Failed to create monitor source: %v", err)
+	}
+	if filters, err := monitor.ParseFilters(config.Filters); err == nil {
+		cm.SetFilters(filters...)
+	} else {
+		log.Printf("Failed to create monitor filters: %v", err)
+	}
+	return cm
+}
+
+// newMonitorSource 创建驱动动画的监控来源，驱动表达式优先于单个指标
+func newMonitorSource(metric, driveExpression string) (monitor.Source, error) {
+	if driveExpression != "" {
+		return monitor.NewExpressionSource(driveExpression)
+	}
+	return monitor.NewSource(metric)
 }
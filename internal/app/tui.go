@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+
+	"github.com/eatmoreapple/go-runcat/internal/config"
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+	"github.com/eatmoreapple/go-runcat/internal/systray"
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+	"github.com/eatmoreapple/go-runcat/internal/tui"
+)
+
+// RunTUI 在终端中运行动画，不依赖系统托盘，直到ctx取消
+// 角色、指标、采样间隔和速度限制均读取自配置，opts为启动时指定的配置选项
+func RunTUI(ctx context.Context, fs fs.FS, w io.Writer, mode tui.Mode, opts config.Options) error {
+	// 创建资源管理器和配置管理器
+	rm := newResourceManager(fs)
+	configManager, err := config.NewManager(rm.Runners(), opts)
+	if err != nil {
+		return err
+	}
+	config := configManager.GetConfig()
+
+	// 终端背景通常较暗，除非明确选择浅色主题，否则使用深色主题的图标
+	themeType := theme.DarkType
+	if theme.Type(config.Theme) == theme.LightType {
+		themeType = theme.LightType
+	}
+
+	// 加载并解码动画帧
+	icons, err := rm.LoadIcons(resource.RunnerType(config.Runner), themeType)
+	if err != nil {
+		return err
+	}
+	frames := make([]image.Image, len(icons))
+	for i, icon := range icons {
+		if frames[i], err = resource.DecodeIcon(icon); err != nil {
+			return fmt.Errorf("decode frame %d of %s: %w", i, config.Runner, err)
+		}
+	}
+
+	// 限速时使用固定的指标值
+	fixedUsage, _ := systray.SpeedLimitType(config.SpeedLimit).FixedUsage()
+
+	terminal, err := tui.New(tui.Options{
+		Output:     w,
+		Frames:     frames,
+		Mode:       mode,
+		FixedUsage: fixedUsage,
+		Timing:     rm.RunnerInfo(resource.RunnerType(config.Runner)).Timing,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 创建并启动CPU监控器
+	cm := newCPUMonitor(config)
+	cm.OnUpdate = terminal.SetUsage
+	cm.Start(ctx)
+	defer cm.Stop()
+
+	return terminal.Run(ctx)
+}
@@ -0,0 +1,580 @@
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eatmoreapple/go-runcat/internal/monitor"
+	"github.com/eatmoreapple/go-runcat/internal/overlay"
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+	"github.com/eatmoreapple/go-runcat/internal/systray"
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Config 应用程序配置
+type Config struct {
+	// 配置文件的版本，用于升级旧的配置文件
+	Version int `mapstructure:"version"`
+	// 当前选择的角色
+	Runner string `mapstructure:"runner"`
+	// 当前主题设置
+	Theme string `mapstructure:"theme"`
+	// 当前速度限制
+	SpeedLimit string `mapstructure:"speed_limit"`
+	// 驱动动画的监控指标
+	Metric string `mapstructure:"metric"`
+	// 组合多个指标的驱动表达式，例如 max(cpu, mem)，非空时优先于Metric
+	DriveExpression string `mapstructure:"drive_expression"`
+	// 回调之前依次应用的平滑过滤器，例如 ["median:3", "hysteresis:5"]
+	Filters []string `mapstructure:"filters"`
+	// 监控采样间隔，例如 3s
+	Interval time.Duration `mapstructure:"interval"`
+	// 在托盘图标上叠加指标的方式：off、digits或bar
+	Overlay string `mapstructure:"overlay"`
+	// 当前使用的配置方案，通过菜单修改单个设置后清空
+	Profile string `mapstructure:"profile"`
+	// 命名的配置方案，名称为小写
+	Profiles map[string]Profile `mapstructure:"profiles"`
+}
+
+// EnvPrefix 覆盖配置项的环境变量前缀，例如 RUNCAT_SPEED_LIMIT 覆盖 speed_limit
+const EnvPrefix = "RUNCAT"
+
+// 可以通过环境变量和命令行选项覆盖的配置项
+var overridableKeys = []string{"runner", "theme", "speed_limit", "metric", "drive_expression", "filters", "interval", "overlay"}
+
+// 修改配置后延迟保存的时间，连续的修改只写入一次
+const saveDelay = 500 * time.Millisecond
+
+// 配置文件无法解析，启动时备份后使用默认配置
+var errCorruptConfig = errors.New("corrupt config file")
+
+// Options 启动时指定的配置选项
+type Options struct {
+	// 配置文件路径，为空时使用用户配置目录下的 go-runcat/config.yaml
+	Path string
+	// 启动时切换到的配置方案，为空时使用 RUNCAT_PROFILE 环境变量
+	Profile string
+	// 命令行指定的配置项，例如 {"speed_limit": "cpu10"}
+	// 优先级：命令行 > 环境变量 > 配置文件 > 默认值；命令行和环境变量的值不会写入配置文件
+	Overrides map[string]string
+}
+
+// Manager 配置管理器
+type Manager struct {
+	// 配置文件路径
+	configPath string
+	// 可选的角色，用于校验runner
+	runners []resource.RunnerType
+	// 保护viper和config的互斥锁
+	mu sync.Mutex
+	// 读取生效配置的viper实例，包含默认值、配置文件、环境变量和命令行选项
+	viper *viper.Viper
+	// 当前生效的配置
+	config Config
+	// 配置文件中的配置，保存时写回，不包含环境变量和命令行选项
+	fileConfig Config
+	// 被环境变量或命令行选项覆盖的配置项，修改只在本次运行中生效
+	overridden []string
+	// 是否已经加载过配置
+	loaded bool
+	// 延迟保存的定时器
+	saveTimer *time.Timer
+	// 是否有尚未写入配置文件的修改
+	dirty bool
+	// 尚未写入配置文件的配置项，重新加载时保留
+	unsaved []string
+	// 最近一次写入配置文件的内容，用于忽略自己写入引起的事件
+	written []byte
+}
+
+// NewManager 创建一个新的配置管理器，runners为可选的角色
+func NewManager(runners []resource.RunnerType, opts Options) (*Manager, error) {
+	configPath := opts.Path
+	if configPath == "" {
+		// 获取用户配置目录
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		configPath = filepath.Join(configDir, "go-runcat", "config.yaml")
+	}
+
+	// 创建配置文件所在的目录
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return nil, err
+	}
+
+	// 创建viper实例
+	v := newConfigViper(configPath)
+
+	// 环境变量覆盖配置文件，version只能在配置文件中设置
+	v.SetEnvPrefix(EnvPrefix)
+	for _, key := range overridableKeys {
+		if err := v.BindEnv(key); err != nil {
+			return nil, err
+		}
+	}
+
+	// 命令行选项覆盖环境变量
+	for key, value := range opts.Overrides {
+		v.Set(key, value)
+	}
+
+	// 记录被覆盖的配置项
+	var overridden []string
+	for _, key := range overridableKeys {
+		_, flag := opts.Overrides[key]
+		_, env := os.LookupEnv(EnvPrefix + "_" + strings.ToUpper(key))
+		if flag || env {
+			overridden = append(overridden, key)
+		}
+	}
+
+	// 创建配置管理器
+	cm := &Manager{
+		configPath: configPath,
+		runners:    runners,
+		viper:      v,
+		overridden: overridden,
+	}
+
+	// 加载配置
+	if err := cm.Load(); err != nil {
+		switch {
+		case os.IsNotExist(err):
+		case errors.Is(err, errCorruptConfig):
+			// 保留损坏的配置文件，使用默认配置
+			backupPath := corruptBackupPath(configPath)
+			if err := os.Rename(configPath, backupPath); err != nil {
+				return nil, err
+			}
+			log.Printf("Warning: %v; using default config, broken file saved as %s", err, backupPath)
+		default:
+			return nil, err
+		}
+		// 写入默认配置后重新加载，以应用环境变量和命令行选项
+		cm.fileConfig = defaultConfig()
+		cm.dirty = true
+		if err := cm.Save(); err != nil {
+			return nil, err
+		}
+		if err := cm.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 切换到指定的配置方案
+	if profile := cmp.Or(opts.Profile, os.Getenv(EnvPrefix+"_PROFILE")); profile != "" {
+		if err := cm.SelectProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	return cm, nil
+}
+
+// 返回损坏的配置文件的备份路径，不覆盖之前的备份
+// 依次尝试 config.yaml.bak、config.yaml.1.bak、config.yaml.2.bak ...
+func corruptBackupPath(configPath string) string {
+	path := configPath + ".bak"
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); err != nil {
+			return path
+		}
+		path = fmt.Sprintf("%s.%d.bak", configPath, i)
+	}
+}
+
+// 默认配置
+func defaultConfig() Config {
+	return Config{
+		Version:    CurrentVersion,
+		Runner:     string(resource.RunnerCat),
+		Theme:      string(theme.AutoType),
+		SpeedLimit: string(systray.SpeedDefault),
+		Metric:     monitor.SourceCPU,
+		Filters:    []string{},
+		Interval:   systray.DefaultInterval,
+		Overlay:    string(overlay.ModeOff),
+	}
+}
+
+// 创建读取配置文件的viper实例并设置默认值
+func newConfigViper(configPath string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("yaml")
+
+	// 设置默认值
+	defaults := defaultConfig()
+	v.SetDefault("version", defaults.Version)
+	v.SetDefault("runner", defaults.Runner)
+	v.SetDefault("theme", defaults.Theme)
+	v.SetDefault("speed_limit", defaults.SpeedLimit)
+	v.SetDefault("metric", defaults.Metric)
+	v.SetDefault("drive_expression", defaults.DriveExpression)
+	v.SetDefault("filters", defaults.Filters)
+	v.SetDefault("interval", defaults.Interval)
+	v.SetDefault("overlay", defaults.Overlay)
+	return v
+}
+
+// Load 升级旧版本的配置文件后加载配置
+func (m *Manager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 检查配置文件是否存在
+	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
+		return err
+	}
+
+	// 升级旧版本的配置文件
+	if err := m.migrate(); err != nil {
+		return err
+	}
+	return m.load()
+}
+
+// 加载并校验配置，失败时保留当前配置，调用时需持有锁
+func (m *Manager) load() error {
+	// 配置文件中的配置，保存时写回
+	file := newConfigViper(m.configPath)
+	if err := file.ReadInConfig(); err != nil {
+		return fmt.Errorf("%w %s: %v", errCorruptConfig, m.configPath, err)
+	}
+	// YAML有效但取值类型错误（例如 interval: 3 seconds）时与校验失败一样报告，不视为损坏
+	var fileConfig Config
+	if err := file.Unmarshal(&fileConfig); err != nil {
+		return fmt.Errorf("invalid config in %s:\n%w", m.configPath, err)
+	}
+
+	// 生效的配置：命令行 > 环境变量 > 配置文件 > 默认值
+	if err := m.viper.ReadInConfig(); err != nil {
+		return err
+	}
+	var config Config
+	if err := m.viper.Unmarshal(&config); err != nil {
+		return fmt.Errorf("invalid config in %s (including %s_* environment variables and flags):\n%w", m.configPath, EnvPrefix, err)
+	}
+	// 尚未保存的修改优先于配置文件，保存时与配置文件中的其他修改一起写入
+	copyFields(&fileConfig, m.fileConfig, m.unsaved)
+	copyFields(&config, m.fileConfig, m.unsaved)
+
+	// 配置方案只能在配置文件中设置
+	config.Profile = fileConfig.Profile
+	config.Profiles = fileConfig.Profiles
+	// 被覆盖的配置项保留本次运行中的值，重新加载时不会撤销菜单中的修改
+	if m.loaded {
+		copyFields(&config, m.config, m.overridden)
+	}
+
+	// 校验所有配置项
+	if err := config.Validate(m.runners); err != nil {
+		return fmt.Errorf("invalid config in %s (including %s_* environment variables and flags):\n%w", m.configPath, EnvPrefix, err)
+	}
+
+	m.config = config
+	m.fileConfig = fileConfig
+	m.loaded = true
+	return nil
+}
+
+// Validate 校验配置，返回所有无效的配置项及其可选值；runners为空时不校验runner
+func (c Config) Validate(runners []resource.RunnerType) error {
+	errs := c.validate(runners)
+	errs = append(errs, c.validateProfiles(runners)...)
+	return errors.Join(errs...)
+}
+
+// 校验配置方案以外的配置项，返回所有无效的配置项
+func (c Config) validate(runners []resource.RunnerType) []error {
+	var errs []error
+	invalid := func(key string, value any, allowed string) {
+		errs = append(errs, fmt.Errorf("%s: invalid value %q (allowed: %s)", key, value, allowed))
+	}
+
+	if c.Version != CurrentVersion {
+		invalid("version", strconv.Itoa(c.Version), strconv.Itoa(CurrentVersion))
+	}
+	if len(runners) > 0 && !slices.Contains(runners, resource.RunnerType(c.Runner)) {
+		invalid("runner", c.Runner, joinValues(runners))
+	}
+	if !slices.Contains(theme.Types, theme.Type(c.Theme)) {
+		invalid("theme", c.Theme, joinValues(theme.Types))
+	}
+	if !slices.Contains(systray.SpeedLimits, systray.SpeedLimitType(c.SpeedLimit)) {
+		invalid("speed_limit", c.SpeedLimit, joinValues(systray.SpeedLimits))
+	}
+	if !slices.Contains(monitor.SourceNames(), c.Metric) {
+		invalid("metric", c.Metric, joinValues(monitor.SourceNames()))
+	}
+	if c.DriveExpression != "" {
+		if _, err := monitor.ParseExpression(c.DriveExpression); err != nil {
+			errs = append(errs, fmt.Errorf("drive_expression: %w", err))
+		}
+	}
+	if c.Interval < monitor.MinInterval {
+		invalid("interval", c.Interval.String(), fmt.Sprintf("%s or longer", monitor.MinInterval))
+	}
+	if _, err := monitor.ParseFilters(c.Filters); err != nil {
+		errs = append(errs, fmt.Errorf("filters: %w", err))
+	}
+	if !slices.Contains(overlay.Modes, overlay.Mode(c.Overlay)) {
+		invalid("overlay", c.Overlay, joinValues(overlay.Modes))
+	}
+	return errs
+}
+
+// 以逗号连接可选值
+func joinValues[T ~string](values []T) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return strings.Join(s, ", ")
+}
+
+// Watch 监听配置文件的修改，重新加载并校验后以修改前后的配置调用onChange
+// 校验失败时保留当前配置并记录日志；内容没有变化时（例如Save写入的文件）不调用
+// ctx取消时停止监听
+func (m *Manager) Watch(ctx context.Context, onChange func(old, new Config)) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Failed to watch config: %v", err)
+		return
+	}
+	// 监听所在目录而不是文件本身，Save通过重命名替换文件后仍能收到修改
+	if err := watcher.Add(filepath.Dir(m.configPath)); err != nil {
+		watcher.Close()
+		log.Printf("Failed to watch config: %v", err)
+		return
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == filepath.Clean(m.configPath) && event.Has(fsnotify.Write|fsnotify.Create) {
+					m.reload(onChange)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Config watcher error: %v", err)
+			}
+		}
+	}()
+}
+
+// 重新加载配置文件，配置有变化时调用onChange
+// 运行期间不升级配置文件，升级只在启动时进行
+func (m *Manager) reload(onChange func(old, new Config)) {
+	// 编辑器保存时可能先清空文件再写入，忽略空文件，等待写入完成后的事件
+	data, err := os.ReadFile(m.configPath)
+	if err == nil && len(data) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	// 忽略Save写入的文件
+	if bytes.Equal(data, m.written) {
+		m.mu.Unlock()
+		return
+	}
+	old := m.config
+	err = m.load()
+	current := m.config
+	m.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Ignoring invalid config change: %v", err)
+		return
+	}
+	if len(ChangedFields(old, current)) > 0 {
+		onChange(old, current)
+	}
+}
+
+// ChangedFields 返回两份配置之间不同的配置项名称，按Config中的字段顺序排列
+func ChangedFields(old, new Config) []string {
+	var fields []string
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := range oldValue.NumField() {
+		a, b := oldValue.Field(i), newValue.Field(i)
+		// nil和空切片视为相同
+		if a.Kind() == reflect.Slice && a.Len() == 0 && b.Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			fields = append(fields, oldValue.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return fields
+}
+
+// 将src中指定名称的字段复制到dst
+func copyFields(dst *Config, src Config, fields []string) {
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src)
+	for _, field := range fields {
+		for i := range srcValue.NumField() {
+			if srcValue.Type().Field(i).Tag.Get("mapstructure") == field {
+				dstValue.Field(i).Set(srcValue.Field(i))
+			}
+		}
+	}
+}
+
+// Save 立即写入尚未保存的修改，没有修改时不改动配置文件，保留手动编辑的注释和格式
+func (m *Manager) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.saveTimer != nil {
+		m.saveTimer.Stop()
+	}
+	if !m.dirty {
+		return nil
+	}
+	return m.save()
+}
+
+// 延迟保存配置，saveDelay内的连续修改只写入一次，调用时需持有锁
+func (m *Manager) scheduleSave() {
+	m.dirty = true
+	if m.saveTimer == nil {
+		m.saveTimer = time.AfterFunc(saveDelay, m.flush)
+		return
+	}
+	m.saveTimer.Reset(saveDelay)
+}
+
+// 写入尚未保存的修改，由延迟保存的定时器调用
+func (m *Manager) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.dirty {
+		return
+	}
+	if err := m.save(); err != nil {
+		log.Printf("Failed to save config: %v", err)
+	}
+}
+
+// 将配置文件中的配置原子地写回，调用时需持有锁
+func (m *Manager) save() error {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.Set("version", CurrentVersion)
+	v.Set("runner", m.fileConfig.Runner)
+	v.Set("theme", m.fileConfig.Theme)
+	v.Set("speed_limit", m.fileConfig.SpeedLimit)
+	v.Set("metric", m.fileConfig.Metric)
+	v.Set("drive_expression", m.fileConfig.DriveExpression)
+	v.Set("filters", m.fileConfig.Filters)
+	v.Set("interval", m.fileConfig.Interval.String())
+	v.Set("overlay", m.fileConfig.Overlay)
+	v.Set("profile", m.fileConfig.Profile)
+	if len(m.fileConfig.Profiles) > 0 {
+		profiles := make(map[string]any, len(m.fileConfig.Profiles))
+		for name, profile := range m.fileConfig.Profiles {
+			profiles[name] = profile.settings()
+		}
+		v.Set("profiles", profiles)
+	}
+
+	// 写入配置文件
+	data, err := writeConfigFile(m.configPath, v)
+	if err != nil {
+		return err
+	}
+	m.written = data
+	m.dirty = false
+	m.unsaved = nil
+	return nil
+}
+
+// GetConfig 获取当前配置
+func (m *Manager) GetConfig() Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.config
+}
+
+// SetConfig 替换当前配置，延迟saveDelay后保存，保存失败时记录日志
+// 只有修改过的配置项写入配置文件；命令行和环境变量覆盖的配置项只在本次运行中生效，不写入配置文件
+func (m *Manager) SetConfig(config Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setConfig(config)
+}
+
+// 替换当前配置并延迟保存，调用时需持有锁
+func (m *Manager) setConfig(config Config) {
+	var saved, skipped []string
+	for _, field := range ChangedFields(m.config, config) {
+		if slices.Contains(m.overridden, field) {
+			skipped = append(skipped, field)
+		} else {
+			saved = append(saved, field)
+			if !slices.Contains(m.unsaved, field) {
+				m.unsaved = append(m.unsaved, field)
+			}
+		}
+	}
+	if len(skipped) > 0 {
+		log.Printf("Not saving %s: set by flags or %s_* environment variables", strings.Join(skipped, ", "), EnvPrefix)
+	}
+	copyFields(&m.fileConfig, config, saved)
+	m.config = config
+	m.scheduleSave()
+}
+
+// SetRunner 设置当前角色
+func (m *Manager) SetRunner(runner resource.RunnerType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	config := m.config
+	config.Runner = string(runner)
+	m.setConfig(config)
+}
+
+// SetTheme 设置当前主题
+func (m *Manager) SetTheme(theme theme.Type) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	config := m.config
+	config.Theme = string(theme)
+	m.setConfig(config)
+}
+
+// SetSpeedLimit 设置当前速度限制
+func (m *Manager) SetSpeedLimit(speedLimit systray.SpeedLimitType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	config := m.config
+	config.SpeedLimit = string(speedLimit)
+	m.setConfig(config)
+}
@@ -0,0 +1,491 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+)
+
+// 在临时目录中写入配置文件，content为空时不创建文件
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// 读取配置文件的内容
+func readConfig(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// 使用path创建配置管理器，测试结束时写入尚未保存的修改
+func newTestManager(t *testing.T, path string, opts Options) *Manager {
+	t.Helper()
+	opts.Path = path
+	m, err := NewManager(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Save() })
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{"default", func(c *Config) {}, nil},
+		{"version", func(c *Config) { c.Version = 7 }, []string{`version: invalid value "7" (allowed: 1)`}},
+		{"theme", func(c *Config) { c.Theme = "dracula" }, []string{`theme: invalid value "dracula" (allowed: auto, light, dark)`}},
+		{"speed limit", func(c *Config) { c.SpeedLimit = "cpu50" }, []string{`speed_limit: invalid value "cpu50" (allowed: default, cpu10, cpu20, cpu30, cpu40)`}},
+		{"metric", func(c *Config) { c.Metric = "gpu" }, []string{`metric: invalid value "gpu"`}},
+		{"drive expression", func(c *Config) { c.DriveExpression = "max(cpu," }, []string{"drive_expression: expression"}},
+		{"interval", func(c *Config) { c.Interval = 500 * time.Millisecond }, []string{`interval: invalid value "500ms" (allowed: 1s or longer)`}},
+		{"filters", func(c *Config) { c.Filters = []string{"kalman:3"} }, []string{"filters:"}},
+		{"overlay", func(c *Config) { c.Overlay = "pie" }, []string{`overlay: invalid value "pie" (allowed: off, digits, bar)`}},
+		{"all reported", func(c *Config) {
+			c.Theme = "x"
+			c.Overlay = "y"
+		}, []string{`theme: invalid value "x"`, `overlay: invalid value "y"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := defaultConfig()
+			tt.modify(&c)
+			err := c.Validate(nil)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRunner(t *testing.T) {
+	c := defaultConfig()
+	c.Runner = "mascot"
+	runners := []resource.RunnerType{resource.RunnerCat, "parrot"}
+	err := c.Validate(runners)
+	if err == nil || !strings.Contains(err.Error(), `runner: invalid value "mascot" (allowed: cat, parrot)`) {
+		t.Errorf("got %v", err)
+	}
+	// 没有可选角色时不校验runner
+	if err := c.Validate(nil); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestJoinValues(t *testing.T) {
+	tests := []struct {
+		values []resource.RunnerType
+		want   string
+	}{
+		{nil, ""},
+		{[]resource.RunnerType{"cat"}, "cat"},
+		{[]resource.RunnerType{"cat", "parrot", "horse"}, "cat, parrot, horse"},
+	}
+	for _, tt := range tests {
+		if got := joinValues(tt.values); got != tt.want {
+			t.Errorf("joinValues(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestNewManagerInvalidConfig(t *testing.T) {
+	content := "version: 1\ntheme: dracula\n"
+	path := writeConfig(t, content)
+	_, err := NewManager(nil, Options{Path: path})
+	if err == nil || !strings.Contains(err.Error(), `theme: invalid value "dracula"`) {
+		t.Fatalf("got %v", err)
+	}
+	// 无效的配置文件保持不变
+	if got := readConfig(t, path); got != content {
+		t.Errorf("config file changed to %q", got)
+	}
+}
+
+func TestNewManagerMissingFile(t *testing.T) {
+	path := writeConfig(t, "")
+	m := newTestManager(t, path, Options{})
+
+	// 写入默认配置
+	if got, want := m.GetConfig().Theme, defaultConfig().Theme; got != want {
+		t.Errorf("theme %q, want %q", got, want)
+	}
+	if !strings.Contains(readConfig(t, path), "version: 1") {
+		t.Errorf("default config not written:\n%s", readConfig(t, path))
+	}
+}
+
+func TestNewManagerCorruptConfig(t *testing.T) {
+	path := writeConfig(t, "")
+	corrupt := []string{"theme: [dark\n", "runner: {cat\n", ": :\n\t- x"}
+	backups := []string{path + ".bak", path + ".1.bak", path + ".2.bak"}
+
+	for i, content := range corrupt {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		m, err := NewManager(nil, Options{Path: path})
+		if err != nil {
+			t.Fatalf("round %d: %v", i, err)
+		}
+		if got, want := m.GetConfig(), defaultConfig(); got.Theme != want.Theme || got.Runner != want.Runner {
+			t.Errorf("round %d: got %+v, want defaults", i, got)
+		}
+		// 之前的备份不被覆盖
+		for j, backup := range backups[:i+1] {
+			if got := readConfig(t, backup); got != corrupt[j] {
+				t.Errorf("round %d: backup %s is %q, want %q", i, backup, got, corrupt[j])
+			}
+		}
+	}
+}
+
+func TestNewManagerDecodeError(t *testing.T) {
+	// YAML有效，但取值无法解码
+	content := "version: 1\ninterval: 3 seconds\ntheme: dark\n"
+	path := writeConfig(t, content)
+
+	_, err := NewManager(nil, Options{Path: path})
+	if err == nil || !strings.Contains(err.Error(), "interval") {
+		t.Fatalf("got %v", err)
+	}
+	if got := readConfig(t, path); got != content {
+		t.Errorf("config file changed to %q", got)
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("unexpected backup: %v", err)
+	}
+}
+
+func TestSetConfigDebounce(t *testing.T) {
+	original := "version: 1\ntheme: auto\n"
+	path := writeConfig(t, original)
+	m := newTestManager(t, path, Options{})
+
+	// 连续的修改合并为一次写入
+	for _, overlay := range []string{"digits", "bar"} {
+		config := m.GetConfig()
+		config.Overlay = overlay
+		m.SetConfig(config)
+	}
+	m.SetTheme("dark")
+	if got := readConfig(t, path); got != original {
+		t.Fatalf("config written before the save delay: %q", got)
+	}
+
+	deadline := time.Now().Add(saveDelay + 2*time.Second)
+	for strings.Contains(readConfig(t, path), "theme: auto") {
+		if time.Now().After(deadline) {
+			t.Fatal("config was not saved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	saved := readConfig(t, path)
+	for _, want := range []string{"theme: dark", "overlay: bar"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved config does not contain %q:\n%s", want, saved)
+		}
+	}
+}
+
+// 记录onChange调用的回调
+type changeRecorder struct {
+	changes chan [2]Config
+}
+
+func newChangeRecorder() *changeRecorder {
+	return &changeRecorder{changes: make(chan [2]Config, 16)}
+}
+
+func (r *changeRecorder) onChange(old, new Config) {
+	r.changes <- [2]Config{old, new}
+}
+
+// 等待下一次配置变化
+func (r *changeRecorder) next(t *testing.T) (old, new Config) {
+	t.Helper()
+	select {
+	case change := <-r.changes:
+		return change[0], change[1]
+	case <-time.After(2 * time.Second):
+		t.Fatal("config change was not reported")
+		return Config{}, Config{}
+	}
+}
+
+// 确认没有配置变化
+func (r *changeRecorder) none(t *testing.T) {
+	t.Helper()
+	select {
+	case change := <-r.changes:
+		t.Fatalf("unexpected config change %v", ChangedFields(change[0], change[1]))
+	default:
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, "version: 1\ntheme: auto\n")
+	m := newTestManager(t, path, Options{})
+	r := newChangeRecorder()
+
+	tests := []struct {
+		name    string
+		content string
+		changed []string
+	}{
+		{"edit", "version: 1\ntheme: dark\ninterval: 5s\n", []string{"theme", "interval"}},
+		{"unchanged", "version: 1\ntheme: dark\ninterval: 5s\n# comment\n", nil},
+		{"invalid ignored", "version: 1\ntheme: dracula\n", nil},
+		{"empty ignored", "", nil},
+		{"edit after invalid", "version: 1\ntheme: light\ninterval: 5s\n", []string{"theme"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m.reload(r.onChange)
+			if tt.changed == nil {
+				r.none(t)
+				return
+			}
+			old, new := r.next(t)
+			if got := ChangedFields(old, new); !slices.Equal(got, tt.changed) {
+				t.Errorf("changed %v, want %v", got, tt.changed)
+			}
+		})
+	}
+}
+
+func TestReloadKeepsUnsavedChanges(t *testing.T) {
+	path := writeConfig(t, "version: 1\ntheme: auto\n")
+	m := newTestManager(t, path, Options{})
+	r := newChangeRecorder()
+
+	// 菜单修改尚未保存时，配置文件被其他程序修改
+	m.SetTheme("dark")
+	if err := os.WriteFile(path, []byte("version: 1\ntheme: auto\noverlay: bar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.reload(r.onChange)
+
+	old, new := r.next(t)
+	if got := ChangedFields(old, new); !slices.Equal(got, []string{"overlay"}) {
+		t.Errorf("changed %v, want [overlay]", got)
+	}
+	if new.Theme != "dark" {
+		t.Errorf("unsaved theme reverted to %q", new.Theme)
+	}
+
+	// 保存时两处修改都写入配置文件
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved := readConfig(t, path)
+	for _, want := range []string{"theme: dark", "overlay: bar"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved config does not contain %q:\n%s", want, saved)
+		}
+	}
+
+	// Save写入的文件不触发重新加载
+	m.SetTheme("light")
+	m.reload(r.onChange)
+	r.none(t)
+	if got := m.GetConfig().Theme; got != "light" {
+		t.Errorf("theme %q, want light", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeConfig(t, "version: 1\ntheme: auto\n")
+	m := newTestManager(t, path, Options{})
+	r := newChangeRecorder()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Watch(ctx, r.onChange)
+
+	if err := writeFileAtomic(path, []byte("version: 1\ntheme: dark\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, new := r.next(t); new.Theme != "dark" {
+		t.Errorf("theme %q, want dark", new.Theme)
+	}
+
+	// 取消后不再重新加载
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	if err := writeFileAtomic(path, []byte("version: 1\ntheme: light\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	r.none(t)
+}
+
+func TestChangedFields(t *testing.T) {
+	base := defaultConfig()
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{"same", func(c *Config) {}, nil},
+		{"nil and empty filters", func(c *Config) { c.Filters = nil }, nil},
+		{"filters", func(c *Config) { c.Filters = []string{"ema:0.5"} }, []string{"filters"}},
+		{"field order", func(c *Config) {
+			c.Overlay = "bar"
+			c.Runner = "parrot"
+			c.Profiles = map[string]Profile{"meeting": {}}
+		}, []string{"runner", "overlay", "profiles"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := base
+			tt.modify(&c)
+			if got := ChangedFields(base, c); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyFields(t *testing.T) {
+	dst := defaultConfig()
+	src := defaultConfig()
+	src.Theme = "dark"
+	src.Interval = 5 * time.Second
+	src.Overlay = "bar"
+
+	copyFields(&dst, src, []string{"theme", "interval", "unknown"})
+	if dst.Theme != "dark" || dst.Interval != 5*time.Second {
+		t.Errorf("fields not copied: %+v", dst)
+	}
+	if dst.Overlay != defaultConfig().Overlay {
+		t.Errorf("overlay copied without being listed")
+	}
+}
+
+func TestSave(t *testing.T) {
+	original := "# 手动编辑的配置\nversion: 1\n\ntheme: dark   # 深色\n"
+	path := writeConfig(t, original)
+	m := newTestManager(t, path, Options{})
+
+	// 没有修改时不改动配置文件
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readConfig(t, path); got != original {
+		t.Fatalf("config file rewritten without changes:\n%s", got)
+	}
+
+	// 有尚未保存的修改时立即写入
+	m.SetTheme("light")
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readConfig(t, path); !strings.Contains(got, "theme: light") {
+		t.Errorf("change not saved:\n%s", got)
+	}
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	t.Setenv(EnvPrefix+"_THEME", "light")
+	t.Setenv(EnvPrefix+"_OVERLAY", "bar")
+	path := writeConfig(t, "version: 1\ntheme: auto\noverlay: digits\nspeed_limit: cpu20\n")
+	m := newTestManager(t, path, Options{Overrides: map[string]string{"theme": "dark", "interval": "5s"}})
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"flag over env", m.GetConfig().Theme, "dark"},
+		{"env over file", m.GetConfig().Overlay, "bar"},
+		{"flag over default", m.GetConfig().Interval, 5 * time.Second},
+		{"file over default", m.GetConfig().SpeedLimit, "cpu20"},
+		{"default", m.GetConfig().Metric, defaultConfig().Metric},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestOverrideVersion(t *testing.T) {
+	for _, value := range []string{"7", "abc"} {
+		t.Setenv(EnvPrefix+"_VERSION", value)
+		path := writeConfig(t, "version: 1\n")
+		m := newTestManager(t, path, Options{})
+		if got := m.GetConfig().Version; got != CurrentVersion {
+			t.Errorf("%s_VERSION=%s: version %d, want %d", EnvPrefix, value, got, CurrentVersion)
+		}
+	}
+}
+
+func TestSetConfigOverriddenKeys(t *testing.T) {
+	t.Setenv(EnvPrefix+"_OVERLAY", "bar")
+	path := writeConfig(t, "version: 1\ntheme: auto\noverlay: \"off\"\ninterval: 3s\n")
+	m := newTestManager(t, path, Options{Overrides: map[string]string{"theme": "dark"}})
+
+	// 菜单修改被覆盖的配置项只在本次运行中生效
+	config := m.GetConfig()
+	config.Theme = "light"
+	config.Overlay = "digits"
+	config.Interval = 5 * time.Second
+	m.SetConfig(config)
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := readConfig(t, path)
+	for _, want := range []string{"theme: auto", "overlay: \"off\"", "interval: 5s"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved config does not contain %q:\n%s", want, saved)
+		}
+	}
+	if m.fileConfig.Theme != "auto" || m.fileConfig.Overlay != "off" {
+		t.Errorf("overridden keys leaked into the file config: %+v", m.fileConfig)
+	}
+
+	// 重新加载时保留本次运行中的值
+	r := newChangeRecorder()
+	if err := os.WriteFile(path, []byte(strings.Replace(saved, "interval: 5s", "interval: 2s", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.reload(r.onChange)
+	_, new := r.next(t)
+	if new.Theme != "light" || new.Overlay != "digits" || new.Interval != 2*time.Second {
+		t.Errorf("unexpected reloaded config %+v", new)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// 将viper中的配置编码后原子地写入path，返回写入的内容
+func writeConfigFile(path string, v *viper.Viper) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := v.WriteConfigTo(&buf); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, buf.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 先写入同一目录下的临时文件，落盘后再重命名为path
+// 写入过程中崩溃或断电时，path要么是旧内容，要么是完整的新内容
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// 失败时删除临时文件
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// CurrentVersion 当前配置文件的版本，没有version字段的配置文件视为版本0
+const CurrentVersion = 1
+
+// migration 将配置文件从from版本升级到from+1版本
+type migration struct {
+	// 升级前的版本
+	from int
+	// 升级内容，记录在日志中
+	description string
+	// 修改配置文件中的原始配置项
+	migrate func(settings map[string]any)
+}
+
+// 按版本排列的升级步骤，增加CurrentVersion时在末尾追加
+var migrations = []migration{
+	{
+		from:        0,
+		description: "normalize theme, speed_limit, metric and overlay values",
+		migrate:     migrateV0,
+	},
+}
+
+// 版本0的配置项接受任意字符串，统一为小写并去除空白
+func migrateV0(settings map[string]any) {
+	for _, key := range []string{"theme", "speed_limit", "metric", "overlay"} {
+		if value, ok := settings[key].(string); ok {
+			settings[key] = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+}
+
+// 将旧版本的配置文件依次升级到CurrentVersion，升级前将原文件备份为 config.yaml.v<版本>.bak
+// 调用时需持有锁
+func (m *Manager) migrate() error {
+	// 只读取配置文件本身，不包含默认值
+	raw := viper.New()
+	raw.SetConfigFile(m.configPath)
+	raw.SetConfigType("yaml")
+	if err := raw.ReadInConfig(); err != nil {
+		return fmt.Errorf("%w %s: %v", errCorruptConfig, m.configPath, err)
+	}
+
+	version := raw.GetInt("version")
+	switch {
+	case version == CurrentVersion:
+		return nil
+	case version < 0 || version > CurrentVersion:
+		return fmt.Errorf("unsupported config version %d in %s (this build supports up to %d)", version, m.configPath, CurrentVersion)
+	}
+
+	settings := raw.AllSettings()
+	for _, migration := range migrations[version:] {
+		migration.migrate(settings)
+		log.Printf("Migrated config from version %d: %s", migration.from, migration.description)
+	}
+	settings["version"] = CurrentVersion
+
+	// 备份原文件
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+	backupPath := fmt.Sprintf("%s.v%d.bak", m.configPath, version)
+	if err := writeFileAtomic(backupPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to back up config before migration: %w", err)
+	}
+
+	// 写回升级后的配置
+	migrated := viper.New()
+	migrated.SetConfigType("yaml")
+	for key, value := range settings {
+		migrated.Set(key, value)
+	}
+	if _, err := writeConfigFile(m.configPath, migrated); err != nil {
+		return err
+	}
+	log.Printf("Upgraded %s to version %d, original saved as %s", m.configPath, CurrentVersion, backupPath)
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	original := "theme: ' Dark '\nspeed_limit: CPU10\nmetric: MEM\noverlay: Bar\nrunner: cat\n"
+	path := writeConfig(t, original)
+	m := newTestManager(t, path, Options{})
+
+	config := m.GetConfig()
+	if config.Version != CurrentVersion || config.Theme != "dark" || config.SpeedLimit != "cpu10" || config.Metric != "mem" || config.Overlay != "bar" {
+		t.Errorf("unexpected migrated config %+v", config)
+	}
+
+	// 原文件备份为 config.yaml.v0.bak
+	if got := readConfig(t, path+".v0.bak"); got != original {
+		t.Errorf("backup %q, want %q", got, original)
+	}
+	migrated := readConfig(t, path)
+	for _, want := range []string{"version: 1", "theme: dark", "speed_limit: cpu10"} {
+		if !strings.Contains(migrated, want) {
+			t.Errorf("migrated file does not contain %q:\n%s", want, migrated)
+		}
+	}
+}
+
+func TestMigrateCurrentVersion(t *testing.T) {
+	original := "version: 1\ntheme: dark\n"
+	path := writeConfig(t, original)
+	newTestManager(t, path, Options{})
+
+	// 当前版本的配置文件不升级也不备份
+	if _, err := os.Stat(path + ".v1.bak"); !os.IsNotExist(err) {
+		t.Errorf("unexpected backup: %v", err)
+	}
+	if got := readConfig(t, path); got != original {
+		t.Errorf("config file changed to %q", got)
+	}
+}
+
+func TestMigrateUnsupportedVersion(t *testing.T) {
+	for _, content := range []string{"version: 2\n", "version: -1\n"} {
+		path := writeConfig(t, content)
+		_, err := NewManager(nil, Options{Path: path})
+		if err == nil || !strings.Contains(err.Error(), "unsupported config version") {
+			t.Errorf("%q: got %v", content, err)
+		}
+		if got := readConfig(t, path); got != content {
+			t.Errorf("%q: config file changed to %q", content, got)
+		}
+	}
+}
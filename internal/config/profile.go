@@ -0,0 +1,104 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+)
+
+// Profile 命名的配置方案，包含一套完整的角色、主题、速度限制和监控设置
+// 未设置的项使用默认值，切换方案后的设置与之前的设置无关
+type Profile struct {
+	// 角色
+	Runner string `mapstructure:"runner"`
+	// 主题设置
+	Theme string `mapstructure:"theme"`
+	// 速度限制
+	SpeedLimit string `mapstructure:"speed_limit"`
+	// 驱动动画的监控指标
+	Metric string `mapstructure:"metric"`
+	// 组合多个指标的驱动表达式，非空时优先于Metric
+	DriveExpression string `mapstructure:"drive_expression"`
+	// 平滑过滤器
+	Filters []string `mapstructure:"filters"`
+	// 监控采样间隔
+	Interval time.Duration `mapstructure:"interval"`
+	// 在托盘图标上叠加指标的方式
+	Overlay string `mapstructure:"overlay"`
+}
+
+// 用方案中的设置替换config中对应的配置项，未设置的项使用默认值
+func (p Profile) apply(config Config) Config {
+	defaults := defaultConfig()
+	config.Runner = cmp.Or(p.Runner, defaults.Runner)
+	config.Theme = cmp.Or(p.Theme, defaults.Theme)
+	config.SpeedLimit = cmp.Or(p.SpeedLimit, defaults.SpeedLimit)
+	config.Metric = cmp.Or(p.Metric, defaults.Metric)
+	config.DriveExpression = p.DriveExpression
+	config.Filters = defaults.Filters
+	if len(p.Filters) > 0 {
+		config.Filters = slices.Clone(p.Filters)
+	}
+	config.Interval = cmp.Or(p.Interval, defaults.Interval)
+	config.Overlay = cmp.Or(p.Overlay, defaults.Overlay)
+	return config
+}
+
+// 写入配置文件的配置项，未设置的项写入默认值
+func (p Profile) settings() map[string]any {
+	config := p.apply(defaultConfig())
+	return map[string]any{
+		"runner":           config.Runner,
+		"theme":            config.Theme,
+		"speed_limit":      config.SpeedLimit,
+		"metric":           config.Metric,
+		"drive_expression": config.DriveExpression,
+		"filters":          config.Filters,
+		"interval":         config.Interval.String(),
+		"overlay":          config.Overlay,
+	}
+}
+
+// ProfileNames 返回所有配置方案的名称，按名称排序
+func (c Config) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
+
+// 校验每个配置方案，错误前加上方案的路径，例如 profiles.meeting.theme
+func (c Config) validateProfiles(runners []resource.RunnerType) []error {
+	var errs []error
+	for _, name := range c.ProfileNames() {
+		config := c.Profiles[name].apply(defaultConfig())
+		for _, err := range config.validate(runners) {
+			errs = append(errs, fmt.Errorf("profiles.%s.%w", name, err))
+		}
+	}
+	if c.Profile != "" && !slices.Contains(c.ProfileNames(), c.Profile) {
+		errs = append(errs, fmt.Errorf("profile: invalid value %q (allowed: %s)", c.Profile, joinValues(c.ProfileNames())))
+	}
+	return errs
+}
+
+// SelectProfile 切换到指定的配置方案并立即保存，名称不区分大小写
+// 方案中的设置写入配置文件，环境变量和命令行指定的配置项仍然优先
+func (m *Manager) SelectProfile(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = strings.ToLower(name)
+	profile, ok := m.fileConfig.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (allowed: %s)", name, joinValues(m.fileConfig.ProfileNames()))
+	}
+	m.fileConfig = profile.apply(m.fileConfig)
+	m.fileConfig.Profile = name
+	if err := m.save(); err != nil {
+		return err
+	}
+	return m.load()
+}
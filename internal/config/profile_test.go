@@ -0,0 +1,158 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 包含两个配置方案的配置文件
+const profilesConfig = `version: 1
+theme: light
+overlay: bar
+profiles:
+  meeting:
+    theme: dark
+    speed_limit: cpu10
+  build:
+    metric: cpumax
+    interval: 1s
+`
+
+func TestProfileApply(t *testing.T) {
+	config := defaultConfig()
+	config.Theme = "light"
+	config.Overlay = "bar"
+	config.DriveExpression = "max(cpu, mem)"
+
+	// 未设置的项使用默认值，与之前的设置无关
+	got := Profile{Metric: "cpumax", Interval: time.Second}.apply(config)
+	want := defaultConfig()
+	want.Metric = "cpumax"
+	want.Interval = time.Second
+	if fields := ChangedFields(want, got); len(fields) > 0 {
+		t.Errorf("unexpected fields %v in %+v", fields, got)
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{"valid", Config{Profiles: map[string]Profile{"meeting": {Theme: "dark"}}, Profile: "meeting"}, nil},
+		{"invalid fields collected", Config{Profiles: map[string]Profile{
+			"meeting": {Theme: "dracula", Overlay: "pie"},
+			"build":   {Interval: time.Millisecond},
+		}}, []string{
+			`profiles.build.interval: invalid value "1ms" (allowed: 1s or longer)`,
+			`profiles.meeting.theme: invalid value "dracula" (allowed: auto, light, dark)`,
+			`profiles.meeting.overlay: invalid value "pie" (allowed: off, digits, bar)`,
+		}},
+		{"unknown current profile", Config{Profiles: map[string]Profile{"meeting": {}}, Profile: "gaming"}, []string{
+			`profile: invalid value "gaming" (allowed: meeting)`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range tt.config.validateProfiles(nil) {
+				got = append(got, err.Error())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	path := writeConfig(t, profilesConfig)
+	m := newTestManager(t, path, Options{})
+
+	if got, want := m.GetConfig().ProfileNames(), []string{"build", "meeting"}; !slices.Equal(got, want) {
+		t.Errorf("profiles %v, want %v", got, want)
+	}
+
+	// 名称不区分大小写，方案中的设置写入配置文件
+	if err := m.SelectProfile("Meeting"); err != nil {
+		t.Fatal(err)
+	}
+	config := m.GetConfig()
+	if config.Profile != "meeting" || config.Theme != "dark" || config.SpeedLimit != "cpu10" || config.Overlay != defaultConfig().Overlay {
+		t.Errorf("unexpected config %+v", config)
+	}
+	saved := readConfig(t, path)
+	for _, want := range []string{"profile: meeting", "theme: dark", "speed_limit: cpu10"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved config does not contain %q:\n%s", want, saved)
+		}
+	}
+
+	// 未知的方案不改变配置
+	err := m.SelectProfile("gaming")
+	if err == nil || !strings.Contains(err.Error(), `unknown profile "gaming" (allowed: build, meeting)`) {
+		t.Errorf("got %v", err)
+	}
+	if got := m.GetConfig().Profile; got != "meeting" {
+		t.Errorf("profile %q, want meeting", got)
+	}
+}
+
+func TestSelectProfileOverrides(t *testing.T) {
+	t.Setenv(EnvPrefix+"_THEME", "light")
+	path := writeConfig(t, profilesConfig)
+	m := newTestManager(t, path, Options{Profile: "meeting"})
+
+	// 环境变量覆盖的配置项仍然优先
+	config := m.GetConfig()
+	if config.Theme != "light" || config.SpeedLimit != "cpu10" {
+		t.Errorf("unexpected config %+v", config)
+	}
+}
+
+func TestStartupProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		flag string
+		env  string
+		want string
+	}{
+		{"flag", "build", "", "build"},
+		{"env", "", "MEETING", "meeting"},
+		{"flag over env", "build", "meeting", "build"},
+		{"none", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvPrefix+"_PROFILE", tt.env)
+			path := writeConfig(t, profilesConfig)
+			m := newTestManager(t, path, Options{Profile: tt.flag})
+			if got := m.GetConfig().Profile; got != tt.want {
+				t.Errorf("profile %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		path := writeConfig(t, profilesConfig)
+		if _, err := NewManager(nil, Options{Path: path, Profile: "gaming"}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestInvalidProfileInConfig(t *testing.T) {
+	path := writeConfig(t, profilesConfig+"  broken:\n    theme: dracula\n    overlay: pie\n")
+	_, err := NewManager(nil, Options{Path: path})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"profiles.broken.theme", "profiles.broken.overlay"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
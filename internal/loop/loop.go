@@ -0,0 +1,79 @@
+package loop
+
+import (
+	"context"
+	"sync"
+)
+
+// Loop 可重复启动和停止的后台循环
+// 零值即可使用，所有方法都可以在多个goroutine中并发调用
+type Loop struct {
+	mu sync.Mutex
+	// 取消当前循环
+	cancel context.CancelFunc
+	// 当前循环退出时关闭
+	done chan struct{}
+}
+
+// Start 在后台运行fn，直到ctx取消或调用Stop
+// 循环已在运行时不做任何操作并返回false
+func (l *Loop) Start(ctx context.Context, fn func(ctx context.Context)) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.running() {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	l.cancel = cancel
+	l.done = done
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		fn(ctx)
+	}()
+	return true
+}
+
+// Stop 停止循环并等待其退出，循环未运行时立即返回
+// 不能在fn内部调用，否则会一直等待
+func (l *Loop) Stop() {
+	l.mu.Lock()
+	cancel, done := l.cancel, l.done
+	l.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// IsRunning 检查循环是否正在运行
+func (l *Loop) IsRunning() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.running()
+}
+
+// Done 返回当前循环退出时关闭的通道，循环从未启动时返回nil
+func (l *Loop) Done() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.done
+}
+
+func (l *Loop) running() bool {
+	if l.done == nil {
+		return false
+	}
+	select {
+	case <-l.done:
+		return false
+	default:
+		return true
+	}
+}
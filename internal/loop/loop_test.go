@@ -0,0 +1,117 @@
+package loop
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 阻塞直到ctx取消的循环体
+func blockUntilDone(started *atomic.Int32) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		started.Add(1)
+		<-ctx.Done()
+	}
+}
+
+func TestStartStopRestart(t *testing.T) {
+	var l Loop
+	var started atomic.Int32
+
+	for i := 1; i <= 3; i++ {
+		if !l.Start(context.Background(), blockUntilDone(&started)) {
+			t.Fatalf("round %d: Start returned false", i)
+		}
+		if l.Start(context.Background(), blockUntilDone(&started)) {
+			t.Fatalf("round %d: second Start while running returned true", i)
+		}
+		if !l.IsRunning() {
+			t.Fatalf("round %d: IsRunning false after Start", i)
+		}
+		l.Stop()
+		if l.IsRunning() {
+			t.Fatalf("round %d: IsRunning true after Stop", i)
+		}
+	}
+
+	if got := started.Load(); got != 3 {
+		t.Fatalf("loop body ran %d times, want 3", got)
+	}
+}
+
+func TestStopWithoutStart(t *testing.T) {
+	var l Loop
+	l.Stop()
+	l.Stop()
+	if l.IsRunning() {
+		t.Fatal("IsRunning true for a loop that never started")
+	}
+}
+
+func TestStopAfterExit(t *testing.T) {
+	var l Loop
+	l.Start(context.Background(), func(ctx context.Context) {})
+	<-l.Done()
+
+	// 循环已自行退出时Stop不能阻塞
+	stopped := make(chan struct{})
+	go func() {
+		l.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop blocked after the loop exited")
+	}
+}
+
+func TestParentContextCancel(t *testing.T) {
+	var l Loop
+	var started atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l.Start(ctx, blockUntilDone(&started))
+	cancel()
+
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("loop did not exit after parent context was canceled")
+	}
+	if l.IsRunning() {
+		t.Fatal("IsRunning true after parent context was canceled")
+	}
+
+	// 父context取消后可以重新启动
+	if !l.Start(context.Background(), blockUntilDone(&started)) {
+		t.Fatal("Start after parent cancel returned false")
+	}
+	l.Stop()
+}
+
+func TestConcurrentStartStop(t *testing.T) {
+	var l Loop
+	var started atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Start(context.Background(), blockUntilDone(&started))
+				_ = l.IsRunning()
+				l.Stop()
+			}
+		}()
+	}
+	wg.Wait()
+
+	l.Stop()
+	if l.IsRunning() {
+		t.Fatal("IsRunning true after all goroutines stopped the loop")
+	}
+}
@@ -1,85 +1,158 @@
 package monitor
 
 import (
+	"context"
+	"sync"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/eatmoreapple/go-runcat/internal/loop"
 )
 
-// CPUMonitor 用于监控CPU使用率
+// MinInterval 最小更新间隔
+const MinInterval = time.Second
+
+// CPUMonitor 用于定期采样监控数据，默认采样CPU使用率
 type CPUMonitor struct {
+	// 采样结果更新时的回调函数，需要在Start之前设置
+	OnUpdate func(sample Sample)
+
+	// 采样循环
+	loop loop.Loop
+	// 运行中修改更新间隔的通道
+	intervalCh chan time.Duration
+
+	// 保护以下字段的互斥锁
+	mu sync.Mutex
 	// 更新间隔
-	Interval time.Duration
-	// CPU使用率变化时的回调函数
-	OnUpdate func(usage float64)
-	// 停止监控的通道
-	stopCh chan struct{}
-	// 是否正在运行
-	running bool
+	interval time.Duration
+	// 当前数据来源
+	source Source
+	// 回调之前应用的过滤链
+	filters FilterChain
 }
 
 // NewCPUMonitor 创建一个新的CPU监控器
 func NewCPUMonitor(interval time.Duration) *CPUMonitor {
-	if interval < time.Second {
-		interval = time.Second
-	}
 	return &CPUMonitor{
-		Interval: interval,
-		stopCh:   make(chan struct{}),
+		interval:   max(interval, MinInterval),
+		intervalCh: make(chan time.Duration, 1),
+		source:     sourceFactories[SourceCPU](),
 	}
 }
 
-// Start 开始监控CPU使用率
-func (m *CPUMonitor) Start() {
-	if m.running || m.OnUpdate == nil {
+// GetInterval 获取更新间隔
+func (m *CPUMonitor) GetInterval() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.interval
+}
+
+// SetInterval 修改更新间隔，运行中修改时立即生效
+func (m *CPUMonitor) SetInterval(interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.interval = max(interval, MinInterval)
+
+	// 丢弃尚未处理的旧值，只保留最新的间隔
+	select {
+	case <-m.intervalCh:
+	default:
+	}
+	m.intervalCh <- m.interval
+}
+
+// SetSource 设置驱动动画的数据来源
+func (m *CPUMonitor) SetSource(source Source) {
+	// 预先采样一次，初始化来源的参照值
+	_, _ = source.Sample()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.source = source
+	// 不同来源的数值不可比较，清除过滤器历史
+	m.filters.Reset()
+}
+
+// GetSource 获取当前数据来源
+func (m *CPUMonitor) GetSource() Source {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.source
+}
+
+// SetFilters 设置回调之前应用的过滤器
+func (m *CPUMonitor) SetFilters(filters ...Filter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.filters = filters
+}
+
+// 采样并应用过滤器
+func (m *CPUMonitor) sample() (Sample, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sample, err := m.source.Sample()
+	if err != nil {
+		return Sample{}, err
+	}
+	sample.Value = clampPercent(m.filters.Apply(sample.Value))
+	return sample, nil
+}
+
+// Start 开始监控，直到ctx取消或调用Stop
+// 已在运行或未设置OnUpdate时不做任何操作
+func (m *CPUMonitor) Start(ctx context.Context) {
+	onUpdate := m.OnUpdate
+	if onUpdate == nil {
 		return
 	}
+	m.loop.Start(ctx, func(ctx context.Context) {
+		m.run(ctx, onUpdate)
+	})
+}
+
+// 采样循环
+func (m *CPUMonitor) run(ctx context.Context, onUpdate func(sample Sample)) {
+	// 启动前的间隔修改已经体现在interval中
+	select {
+	case <-m.intervalCh:
+	default:
+	}
 
-	m.running = true
-	go func() {
-		ticker := time.NewTicker(m.Interval)
-		defer ticker.Stop()
-
-		// 第一次读取CPU使用率（丢弃，因为第一次读取通常不准确）
-		_, _ = cpu.Percent(0, false)
-
-		for {
-			select {
-			case <-ticker.C:
-				percentages, err := cpu.Percent(0, false)
-				if err != nil || len(percentages) == 0 {
-					continue
-				}
-				// 获取总体CPU使用率
-				usage := percentages[0]
-
-				// 确保使用率在0-100之间
-				if usage < 0 {
-					usage = 0
-				} else if usage > 100 {
-					usage = 100
-				}
-
-				// 调用回调函数
-				m.OnUpdate(usage)
-
-			case <-m.stopCh:
-				m.running = false
-				return
+	ticker := time.NewTicker(m.GetInterval())
+	defer ticker.Stop()
+
+	// 第一次采样（丢弃，因为第一次读取通常不准确，吞吐量类来源也需要参照值）
+	_, _ = m.GetSource().Sample()
+
+	for {
+		select {
+		case <-ticker.C:
+			sample, err := m.sample()
+			if err != nil {
+				continue
 			}
+
+			// 调用回调函数
+			onUpdate(sample)
+
+		case interval := <-m.intervalCh:
+			ticker.Reset(interval)
+
+		case <-ctx.Done():
+			return
 		}
-	}()
+	}
 }
 
-// Stop 停止监控CPU使用率
+// Stop 停止监控并等待采样循环退出，未运行时立即返回
 func (m *CPUMonitor) Stop() {
-	if !m.running {
-		return
-	}
-	m.stopCh <- struct{}{}
+	m.loop.Stop()
 }
 
 // IsRunning 检查监控器是否正在运行
 func (m *CPUMonitor) IsRunning() bool {
-	return m.running
+	return m.loop.IsRunning()
 }
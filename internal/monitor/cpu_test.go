@@ -0,0 +1,208 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource 返回固定值的来源
+type fakeSource struct {
+	mu    sync.Mutex
+	value float64
+	calls int
+}
+
+func (s *fakeSource) Name() string { return "fake" }
+
+func (s *fakeSource) Unit() string { return "%" }
+
+func (s *fakeSource) Sample() (Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return Sample{Source: "fake", Unit: "%", Value: s.value, Raw: s.value}, nil
+}
+
+// 创建一个采样间隔很短的监控器，updates接收每次回调
+func newTestMonitor(value float64) (*CPUMonitor, <-chan Sample) {
+	updates := make(chan Sample, 16)
+	m := NewCPUMonitor(MinInterval)
+	// 绕过最小间隔，缩短测试时间
+	m.interval = 5 * time.Millisecond
+	m.SetSource(&fakeSource{value: value})
+	m.OnUpdate = func(sample Sample) {
+		select {
+		case updates <- sample:
+		default:
+		}
+	}
+	return m, updates
+}
+
+func waitForUpdate(t *testing.T, updates <-chan Sample) Sample {
+	t.Helper()
+	select {
+	case sample := <-updates:
+		return sample
+	case <-time.After(time.Second):
+		t.Fatal("no update received")
+		return Sample{}
+	}
+}
+
+func TestCPUMonitorStartStopRestart(t *testing.T) {
+	m, updates := newTestMonitor(42)
+
+	for i := 0; i < 3; i++ {
+		m.Start(context.Background())
+		if !m.IsRunning() {
+			t.Fatalf("round %d: IsRunning false after Start", i)
+		}
+		if got := waitForUpdate(t, updates); got.Value != 42 {
+			t.Fatalf("round %d: got value %v, want 42", i, got.Value)
+		}
+		m.Stop()
+		if m.IsRunning() {
+			t.Fatalf("round %d: IsRunning true after Stop", i)
+		}
+		// 停止后不应再有回调
+		for len(updates) > 0 {
+			<-updates
+		}
+		select {
+		case <-updates:
+			t.Fatalf("round %d: update received after Stop", i)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// 重复Stop不能阻塞
+	m.Stop()
+}
+
+func TestCPUMonitorContextCancel(t *testing.T) {
+	m, updates := newTestMonitor(10)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.Start(ctx)
+	waitForUpdate(t, updates)
+	cancel()
+
+	deadline := time.After(time.Second)
+	for m.IsRunning() {
+		select {
+		case <-deadline:
+			t.Fatal("monitor still running after context was canceled")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	// 已经退出时Stop立即返回
+	m.Stop()
+}
+
+func TestCPUMonitorWithoutCallback(t *testing.T) {
+	m := NewCPUMonitor(MinInterval)
+	m.Start(context.Background())
+	if m.IsRunning() {
+		t.Fatal("monitor started without OnUpdate")
+	}
+	m.Stop()
+}
+
+func TestCPUMonitorConcurrentChanges(t *testing.T) {
+	m, _ := newTestMonitor(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			m.Start(ctx)
+			time.Sleep(time.Millisecond)
+			m.Stop()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			m.SetSource(&fakeSource{value: float64(i)})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			m.SetFilters(NewEMAFilter(0.5))
+			_ = m.GetSource()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			m.SetInterval(MinInterval)
+			_ = m.GetInterval()
+			_ = m.IsRunning()
+		}
+	}()
+	wg.Wait()
+
+	m.Stop()
+	if m.IsRunning() {
+		t.Fatal("monitor still running after Stop")
+	}
+}
+
+// halfFilter 将采样值减半
+type halfFilter struct{}
+
+func (halfFilter) Apply(value float64) float64 { return value / 2 }
+
+func (halfFilter) Reset() {}
+
+func TestCPUMonitorAppliesFilters(t *testing.T) {
+	m, updates := newTestMonitor(80)
+	m.SetFilters(halfFilter{})
+
+	m.Start(context.Background())
+	defer m.Stop()
+
+	// 过滤器只作用于归一化值，原始值保持不变
+	sample := waitForUpdate(t, updates)
+	if sample.Value != 40 || sample.Raw != 80 {
+		t.Fatalf("got value %v raw %v, want value 40 raw 80", sample.Value, sample.Raw)
+	}
+}
+
+func TestCPUMonitorDefaultSource(t *testing.T) {
+	orig := cpuPercent
+	cpuPercent = func() ([]float64, error) {
+		return []float64{20, 60}, nil
+	}
+	t.Cleanup(func() {
+		cpuPercent = orig
+		coreReading.time = time.Time{}
+	})
+	coreReading.time = time.Time{}
+
+	// 未调用SetSource时使用默认的CPU来源
+	m := NewCPUMonitor(MinInterval)
+	m.interval = 5 * time.Millisecond
+	updates := make(chan Sample, 1)
+	m.OnUpdate = func(sample Sample) {
+		select {
+		case updates <- sample:
+		default:
+		}
+	}
+	m.Start(context.Background())
+	defer m.Stop()
+
+	sample := waitForUpdate(t, updates)
+	if sample.Source != SourceCPU || sample.Value != 40 {
+		t.Errorf("got %+v, want cpu sample of 40", sample)
+	}
+}
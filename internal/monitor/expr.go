@@ -0,0 +1,415 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SourceExpression 表达式组合来源的名称
+const SourceExpression = "expr"
+
+// Expression 由多个监控来源组合而成的表达式，例如 max(cpu, mem) 或 0.7*cpu + 0.3*net
+type Expression struct {
+	// 原始表达式文本
+	text string
+	// 语法树
+	root exprNode
+	// 引用的来源名称
+	sources []string
+}
+
+// ParseExpression 解析表达式，引用未知来源或语法错误时返回错误
+func ParseExpression(text string) (*Expression, error) {
+	p := &exprParser{text: text, sources: make(map[string]struct{})}
+	p.next()
+
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	sources := make([]string, 0, len(p.sources))
+	for name := range p.sources {
+		sources = append(sources, name)
+	}
+	sort.Strings(sources)
+
+	return &Expression{text: text, root: root, sources: sources}, nil
+}
+
+// String 返回原始表达式文本
+func (e *Expression) String() string {
+	return e.text
+}
+
+// Sources 返回表达式引用的来源名称
+func (e *Expression) Sources() []string {
+	return e.sources
+}
+
+// Eval 使用各来源的归一化值计算表达式
+func (e *Expression) Eval(values map[string]float64) (float64, error) {
+	return e.root.eval(values)
+}
+
+// ExpressionSource 按表达式组合多个来源的采样结果
+type ExpressionSource struct {
+	expr    *Expression
+	sources []Source
+}
+
+// NewExpressionSource 解析表达式并创建组合来源
+func NewExpressionSource(text string) (*ExpressionSource, error) {
+	expr, err := ParseExpression(text)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]Source, 0, len(expr.Sources()))
+	for _, name := range expr.Sources() {
+		source, err := NewSource(name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return &ExpressionSource{expr: expr, sources: sources}, nil
+}
+
+func (s *ExpressionSource) Name() string { return SourceExpression }
+
+func (s *ExpressionSource) Unit() string { return "%" }
+
+// Expression 返回组合来源使用的表达式
+func (s *ExpressionSource) Expression() *Expression {
+	return s.expr
+}
+
+func (s *ExpressionSource) Sample() (Sample, error) {
+	values := make(map[string]float64, len(s.sources))
+	parts := make([]Sample, 0, len(s.sources))
+	for _, source := range s.sources {
+		sample, err := source.Sample()
+		if err != nil {
+			return Sample{}, fmt.Errorf("sample %s: %w", source.Name(), err)
+		}
+		values[source.Name()] = sample.Value
+		parts = append(parts, sample)
+	}
+
+	result, err := s.expr.Eval(values)
+	if err != nil {
+		return Sample{}, err
+	}
+	value := clampPercent(result)
+	return Sample{Source: SourceExpression, Unit: s.Unit(), Value: value, Raw: value, Parts: parts}, nil
+}
+
+// 表达式函数，参数个数至少为1
+var exprFuncs = map[string]func(args []float64) float64{
+	"max": func(args []float64) float64 {
+		result := args[0]
+		for _, v := range args[1:] {
+			result = max(result, v)
+		}
+		return result
+	},
+	"min": func(args []float64) float64 {
+		result := args[0]
+		for _, v := range args[1:] {
+			result = min(result, v)
+		}
+		return result
+	},
+	"avg": func(args []float64) float64 {
+		var sum float64
+		for _, v := range args {
+			sum += v
+		}
+		return sum / float64(len(args))
+	},
+}
+
+// exprNode 语法树节点
+type exprNode interface {
+	eval(values map[string]float64) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type sourceNode string
+
+func (n sourceNode) eval(values map[string]float64) (float64, error) {
+	v, ok := values[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("no sample for source: %s", string(n))
+	}
+	return v, nil
+}
+
+type negNode struct {
+	operand exprNode
+}
+
+func (n negNode) eval(values map[string]float64) (float64, error) {
+	v, err := n.operand.eval(values)
+	return -v, err
+}
+
+type binaryNode struct {
+	op          byte
+	left, right exprNode
+}
+
+func (n binaryNode) eval(values map[string]float64) (float64, error) {
+	l, err := n.left.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.right.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	default:
+		// 除数为0时结果记为0，避免动画停滞
+		if r == 0 {
+			return 0, nil
+		}
+		return l / r, nil
+	}
+}
+
+type callNode struct {
+	fn   func(args []float64) float64
+	args []exprNode
+}
+
+func (n callNode) eval(values map[string]float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(values)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return n.fn(args), nil
+}
+
+// 词法单元类型
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// exprParser 递归下降解析器
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | source | func "(" sum { "," sum } ")" | "(" sum ")"
+type exprParser struct {
+	text    string
+	pos     int
+	tok     token
+	sources map[string]struct{}
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return p.errorAt(p.tok.pos, format, args...)
+}
+
+func (p *exprParser) errorAt(pos int, format string, args ...any) error {
+	return fmt.Errorf("expression %q at position %d: %s", p.text, pos+1, fmt.Sprintf(format, args...))
+}
+
+// next 读取下一个词法单元
+func (p *exprParser) next() {
+	for p.pos < len(p.text) && unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.text) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.text[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.text) && (p.text[p.pos] >= '0' && p.text[p.pos] <= '9' || p.text[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.text[start:p.pos], pos: start}
+	case unicode.IsLetter(rune(c)) || c == '_':
+		for p.pos < len(p.text) && (unicode.IsLetter(rune(p.text[p.pos])) || unicode.IsDigit(rune(p.text[p.pos])) || p.text[p.pos] == '_') {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.text[start:p.pos], pos: start}
+	default:
+		p.pos++
+		kind := tokInvalid
+		switch c {
+		case '+', '-', '*', '/':
+			kind = tokOp
+		case '(':
+			kind = tokLParen
+		case ')':
+			kind = tokRParen
+		case ',':
+			kind = tokComma
+		}
+		p.tok = token{kind: kind, text: string(c), pos: start}
+	}
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && (p.tok.text == "+" || p.tok.text == "-") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && (p.tok.text == "*" || p.tok.text == "/") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.tok.kind == tokOp && p.tok.text == "-" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	switch p.tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(p.tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", p.tok)
+		}
+		p.next()
+		return numberNode(v), nil
+
+	case tokIdent:
+		name, pos := strings.ToLower(p.tok.text), p.tok.pos
+		p.next()
+
+		// 函数调用
+		if p.tok.kind == tokLParen {
+			fn, ok := exprFuncs[name]
+			if !ok {
+				return nil, p.errorAt(pos, "unknown function %q (allowed: avg, max, min)", name)
+			}
+			p.next()
+			if p.tok.kind == tokRParen {
+				return nil, p.errorf("%s() requires at least one argument", name)
+			}
+			var args []exprNode
+			for {
+				arg, err := p.parseSum()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if p.tok.kind != tokComma {
+					break
+				}
+				p.next()
+			}
+			if p.tok.kind != tokRParen {
+				return nil, p.errorf("expected \")\" but found %s", p.tok)
+			}
+			p.next()
+			return callNode{fn: fn, args: args}, nil
+		}
+
+		// 来源名称
+		if _, ok := sourceFactories[name]; !ok {
+			return nil, p.errorAt(pos, "unknown source %q (allowed: %s)", name, strings.Join(SourceNames(), ", "))
+		}
+		p.sources[name] = struct{}{}
+		return sourceNode(name), nil
+
+	case tokLParen:
+		p.next()
+		node, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected \")\" but found %s", p.tok)
+		}
+		p.next()
+		return node, nil
+
+	default:
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+}
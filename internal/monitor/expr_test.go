@@ -0,0 +1,183 @@
+package monitor
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 各来源的归一化值
+var exprValues = map[string]float64{
+	SourceCPU:     40,
+	SourceCPUMax:  90,
+	SourceMemory:  60,
+	SourceDisk:    0,
+	SourceNetwork: 10,
+	SourceLoad:    25,
+}
+
+func TestExpressionEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		// 优先级和结合性
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"48 / 4 / 2", 6},
+		{"2 * 3 + 4 * 5", 26},
+		{"0.7*cpu + 0.3*net", 31},
+		{"cpu - mem / 2", 10},
+		// 一元负号
+		{"-cpu + 100", 60},
+		{"--cpu", 40},
+		{"-(cpu - mem)", 20},
+		{"2 * -3", -6},
+		// 函数
+		{"max(cpu, mem)", 60},
+		{"min(cpu, mem, net)", 10},
+		{"avg(cpu, mem)", 50},
+		{"max(cpu)", 40},
+		{"avg(max(cpu, mem), min(net, load))", 35},
+		{"MAX(CPU, Mem)", 60},
+		// 除数为0时记为0
+		{"cpu / disk", 0},
+		{"cpu / (net - 10)", 0},
+		{".5 * cpu", 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseExpression: %v", err)
+			}
+			got, err := expr.Eval(exprValues)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpressionSources(t *testing.T) {
+	expr, err := ParseExpression("max(net, cpu) + 0.1*net - mem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := expr.Sources(), []string{SourceCPU, SourceMemory, SourceNetwork}; !slices.Equal(got, want) {
+		t.Errorf("sources %v, want %v", got, want)
+	}
+	if expr.String() != "max(net, cpu) + 0.1*net - mem" {
+		t.Errorf("String() = %q", expr.String())
+	}
+	if _, err := expr.Eval(map[string]float64{SourceCPU: 1}); err == nil {
+		t.Error("Eval accepted missing samples")
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "position 1: unexpected end of expression"},
+		{"gpu", `position 1: unknown source "gpu"`},
+		{"cpu + gpu", `position 7: unknown source "gpu"`},
+		{"sum(cpu, mem)", `position 1: unknown function "sum"`},
+		{"max()", "position 5: max() requires at least one argument"},
+		{"avg( )", "position 6: avg() requires at least one argument"},
+		{"max(cpu,)", `position 9: unexpected ")"`},
+		{"max(cpu mem)", `position 9: expected ")" but found "mem"`},
+		{"(cpu + mem", `position 11: expected ")" but found end of expression`},
+		{"cpu +", "position 6: unexpected end of expression"},
+		{"cpu mem", `position 5: unexpected "mem"`},
+		{"cpu % 2", `position 5: unexpected "%"`},
+		{"1..2 * cpu", `position 1: invalid number "1..2"`},
+		{"*cpu", `position 1: unexpected "*"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseExpression(tt.expr)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// namedSource 返回固定值的指定来源
+type namedSource struct {
+	name  string
+	value float64
+}
+
+func (s namedSource) Name() string { return s.name }
+
+func (s namedSource) Unit() string { return "%" }
+
+func (s namedSource) Sample() (Sample, error) {
+	return Sample{Source: s.name, Unit: "%", Value: s.value, Raw: s.value}, nil
+}
+
+func TestExpressionSourceClamps(t *testing.T) {
+	// 表达式不支持指数写法，连乘得到无穷大
+	huge := strings.Repeat(" * 100000000000000000000", 16)
+	tests := []struct {
+		expr   string
+		values map[string]float64
+		want   float64
+	}{
+		{"cpu + mem", map[string]float64{SourceCPU: 80, SourceMemory: 70}, 100},
+		{"cpu - mem", map[string]float64{SourceCPU: 10, SourceMemory: 70}, 0},
+		{"0.5 * cpu", map[string]float64{SourceCPU: 50}, 25},
+		// 无穷大相减得到NaN，记为0
+		{"cpu" + huge + " - mem" + huge, map[string]float64{SourceCPU: 1, SourceMemory: 1}, 0},
+		{"cpu" + huge, map[string]float64{SourceCPU: 1}, 100},
+		{"-cpu" + huge, map[string]float64{SourceCPU: 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			source, err := NewExpressionSource(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 使用固定值的来源代替真实采样
+			for i, s := range source.sources {
+				source.sources[i] = namedSource{name: s.Name(), value: tt.values[s.Name()]}
+			}
+			sample, err := source.Sample()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sample.Value != tt.want || sample.Raw != tt.want {
+				t.Errorf("value %v raw %v, want %v", sample.Value, sample.Raw, tt.want)
+			}
+			if sample.Source != SourceExpression || len(sample.Parts) != len(tt.values) {
+				t.Errorf("unexpected sample %+v", sample)
+			}
+		})
+	}
+
+	if _, err := NewExpressionSource("max(cpu, gpu)"); err == nil {
+		t.Error("NewExpressionSource accepted an unknown source")
+	}
+}
+
+func TestClampPercent(t *testing.T) {
+	for in, want := range map[float64]float64{
+		-1: 0, 0: 0, 55.5: 55.5, 100: 100, 250: 100,
+		math.Inf(1): 100, math.Inf(-1): 0, math.NaN(): 0,
+	} {
+		if got := clampPercent(in); got != want {
+			t.Errorf("clampPercent(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
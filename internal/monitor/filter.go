@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Filter 在回调之前对采样值进行平滑处理
+type Filter interface {
+	// Apply 输入一个新采样值，返回处理后的值
+	Apply(value float64) float64
+	// Reset 清除历史状态
+	Reset()
+}
+
+// 滑动窗口的最大大小，按最小更新间隔约为一小时
+const maxFilterWindow = 3600
+
+// ParseFilter 解析过滤器配置，格式为 <类型>:<参数>
+//
+//	ema:0.3         指数移动平均，参数为平滑系数 (0, 1]
+//	mean:5          滑动窗口平均值，参数为窗口大小 [1, 3600]
+//	median:5        滑动窗口中位数，参数为窗口大小 [1, 3600]
+//	hysteresis:5    滞后区间，输入偏离上次输出超过该值时才更新
+func ParseFilter(spec string) (Filter, error) {
+	kind, arg, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid filter %q: expected <type>:<param>", spec)
+	}
+	param, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", spec, err)
+	}
+	// NaN和无穷大会使输出停止更新或变为NaN
+	if math.IsNaN(param) || math.IsInf(param, 0) {
+		return nil, fmt.Errorf("invalid filter %q: parameter must be a finite number", spec)
+	}
+
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	switch kind {
+	case "ema":
+		if param <= 0 || param > 1 {
+			return nil, fmt.Errorf("invalid filter %q: alpha must be in (0, 1]", spec)
+		}
+		return NewEMAFilter(param), nil
+	case "mean", "median":
+		if param < 1 || param > maxFilterWindow || param != math.Trunc(param) {
+			return nil, fmt.Errorf("invalid filter %q: window must be an integer between 1 and %d", spec, maxFilterWindow)
+		}
+		if kind == "mean" {
+			return NewMeanFilter(int(param)), nil
+		}
+		return NewMedianFilter(int(param)), nil
+	case "hysteresis":
+		if param < 0 {
+			return nil, fmt.Errorf("invalid filter %q: band must not be negative", spec)
+		}
+		return NewHysteresisFilter(param), nil
+	default:
+		return nil, fmt.Errorf("invalid filter %q: unknown type %q (allowed: ema, mean, median, hysteresis)", spec, kind)
+	}
+}
+
+// ParseFilters 解析多个过滤器配置，按顺序组成过滤链
+func ParseFilters(specs []string) (FilterChain, error) {
+	chain := make(FilterChain, 0, len(specs))
+	for _, spec := range specs {
+		filter, err := ParseFilter(spec)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, filter)
+	}
+	return chain, nil
+}
+
+// FilterChain 按顺序应用多个过滤器
+type FilterChain []Filter
+
+// Apply 依次应用所有过滤器
+func (c FilterChain) Apply(value float64) float64 {
+	for _, f := range c {
+		value = f.Apply(value)
+	}
+	return value
+}
+
+// Reset 清除所有过滤器的历史状态
+func (c FilterChain) Reset() {
+	for _, f := range c {
+		f.Reset()
+	}
+}
+
+// emaFilter 指数移动平均
+type emaFilter struct {
+	alpha   float64
+	value   float64
+	started bool
+}
+
+// NewEMAFilter 创建指数移动平均过滤器，alpha越小越平滑
+func NewEMAFilter(alpha float64) Filter {
+	return &emaFilter{alpha: alpha}
+}
+
+func (f *emaFilter) Apply(value float64) float64 {
+	if !f.started {
+		f.value = value
+		f.started = true
+		return value
+	}
+	f.value = f.alpha*value + (1-f.alpha)*f.value
+	return f.value
+}
+
+func (f *emaFilter) Reset() {
+	f.started = false
+	f.value = 0
+}
+
+// windowFilter 滑动窗口过滤器
+type windowFilter struct {
+	size   int
+	values []float64
+	reduce func(values []float64) float64
+}
+
+// NewMeanFilter 创建滑动窗口平均值过滤器
+func NewMeanFilter(size int) Filter {
+	return &windowFilter{size: max(size, 1), reduce: mean}
+}
+
+// NewMedianFilter 创建滑动窗口中位数过滤器
+func NewMedianFilter(size int) Filter {
+	return &windowFilter{size: max(size, 1), reduce: median}
+}
+
+func (f *windowFilter) Apply(value float64) float64 {
+	f.values = append(f.values, value)
+	if len(f.values) > f.size {
+		f.values = f.values[len(f.values)-f.size:]
+	}
+	return f.reduce(f.values)
+}
+
+func (f *windowFilter) Reset() {
+	f.values = nil
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// hysteresisFilter 滞后区间，避免在相近的值之间来回跳动
+type hysteresisFilter struct {
+	band    float64
+	value   float64
+	started bool
+}
+
+// NewHysteresisFilter 创建滞后过滤器，输入偏离上次输出超过band时才更新输出
+func NewHysteresisFilter(band float64) Filter {
+	return &hysteresisFilter{band: band}
+}
+
+func (f *hysteresisFilter) Apply(value float64) float64 {
+	if !f.started || math.Abs(value-f.value) > f.band {
+		f.value = value
+		f.started = true
+	}
+	return f.value
+}
+
+func (f *hysteresisFilter) Reset() {
+	f.started = false
+	f.value = 0
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"math"
+	"testing"
+)
+
+func applyAll(f Filter, inputs []float64) []float64 {
+	outputs := make([]float64, len(inputs))
+	for i, v := range inputs {
+		outputs[i] = f.Apply(v)
+	}
+	return outputs
+}
+
+func assertSequence(t *testing.T, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("value %d: got %v, want %v (sequence %v)", i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		inputs []float64
+		want   []float64
+	}{
+		{
+			name:   "ema starts at first sample",
+			filter: NewEMAFilter(0.5),
+			inputs: []float64{10, 20, 20, 0},
+			want:   []float64{10, 15, 17.5, 8.75},
+		},
+		{
+			name:   "ema alpha 1 passes through",
+			filter: NewEMAFilter(1),
+			inputs: []float64{10, 90, 30},
+			want:   []float64{10, 90, 30},
+		},
+		{
+			name:   "mean over window",
+			filter: NewMeanFilter(3),
+			inputs: []float64{3, 6, 9, 30, 0},
+			want:   []float64{3, 4.5, 6, 15, 13},
+		},
+		{
+			name:   "median rejects spikes",
+			filter: NewMedianFilter(3),
+			inputs: []float64{10, 100, 12, 11, 0, 13},
+			want:   []float64{10, 55, 12, 12, 11, 11},
+		},
+		{
+			name:   "hysteresis holds inside band",
+			filter: NewHysteresisFilter(5),
+			inputs: []float64{50, 53, 47, 56, 52, 51, 40},
+			want:   []float64{50, 50, 50, 56, 56, 56, 40},
+		},
+		{
+			name:   "chain applies in order",
+			filter: FilterChain{NewMedianFilter(3), NewHysteresisFilter(2)},
+			inputs: []float64{10, 100, 12, 11, 0, 13},
+			want:   []float64{10, 55, 12, 12, 12, 12},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSequence(t, applyAll(tt.filter, tt.inputs), tt.want)
+		})
+	}
+}
+
+func TestFilterReset(t *testing.T) {
+	filters := map[string]Filter{
+		"ema":        NewEMAFilter(0.1),
+		"mean":       NewMeanFilter(4),
+		"median":     NewMedianFilter(4),
+		"hysteresis": NewHysteresisFilter(50),
+	}
+	for name, f := range filters {
+		t.Run(name, func(t *testing.T) {
+			applyAll(f, []float64{90, 80, 70})
+			f.Reset()
+			if got := f.Apply(5); got != 5 {
+				t.Fatalf("after Reset got %v, want 5", got)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	valid := []string{"ema:0.3", "EMA:1", "mean:5", "MEDIAN:1", "hysteresis:0", " hysteresis : 2.5 ", "median:3600"}
+	for _, spec := range valid {
+		if _, err := ParseFilter(spec); err != nil {
+			t.Errorf("ParseFilter(%q) returned error: %v", spec, err)
+		}
+	}
+
+	invalid := []string{"", "ema", "ema:0", "ema:1.5", "mean:0", "median:2.5", "hysteresis:-1", "kalman:3", "mean:abc",
+		"hysteresis:NaN", "ema:NaN", "mean:Inf", "median:1e300", "median:3601", "hysteresis:+Inf"}
+	for _, spec := range invalid {
+		if _, err := ParseFilter(spec); err == nil {
+			t.Errorf("ParseFilter(%q) returned no error", spec)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+const (
+	// SourceCPU CPU使用率
+	SourceCPU = "cpu"
+	// SourceCPUMax 最繁忙核心的CPU使用率
+	SourceCPUMax = "cpumax"
+	// SourceMemory 内存使用率
+	SourceMemory = "mem"
+	// SourceDisk 磁盘读写吞吐量
+	SourceDisk = "disk"
+	// SourceNetwork 网络收发吞吐量
+	SourceNetwork = "net"
+	// SourceLoad 系统平均负载
+	SourceLoad = "load"
+)
+
+const (
+	// 磁盘吞吐量达到该值时归一化为100 (200 MB/s)
+	diskFullScale = 200 * 1024 * 1024
+	// 网络吞吐量达到该值时归一化为100 (12.5 MB/s，即100 Mbit/s)
+	netFullScale = 12.5 * 1024 * 1024
+)
+
+// Sample 一次采样结果
+type Sample struct {
+	// 来源名称
+	Source string
+	// 原始值的单位
+	Unit string
+	// 归一化到0-100的值，用于驱动动画
+	Value float64
+	// 原始值
+	Raw float64
+	// 每个CPU核心的使用率，仅CPU来源提供
+	Cores []float64
+	// 组合来源的各组成部分
+	Parts []Sample
+}
+
+// String 返回适合显示在提示文本中的采样描述
+func (s Sample) String() string {
+	text := fmt.Sprintf("%s: %s", SourceLabel(s.Source), formatRaw(s.Raw, s.Unit))
+	if len(s.Parts) == 0 {
+		return text
+	}
+	parts := make([]string, len(s.Parts))
+	for i, part := range s.Parts {
+		parts[i] = part.String()
+	}
+	return text + " (" + strings.Join(parts, ", ") + ")"
+}
+
+// CoreUsages 返回采样（或其组成部分）中的每核心使用率
+func (s Sample) CoreUsages() []float64 {
+	if len(s.Cores) > 0 {
+		return s.Cores
+	}
+	for _, part := range s.Parts {
+		if cores := part.CoreUsages(); len(cores) > 0 {
+			return cores
+		}
+	}
+	return nil
+}
+
+// Source 可驱动动画的监控数据来源
+type Source interface {
+	// Name 来源名称
+	Name() string
+	// Unit 原始值的单位
+	Unit() string
+	// Sample 采样一次，返回归一化值和原始值
+	Sample() (Sample, error)
+}
+
+// 来源构造函数
+var sourceFactories = map[string]func() Source{
+	SourceCPU:     func() Source { return &cpuSource{read: readCoreUsages} },
+	SourceCPUMax:  func() Source { return &cpuSource{hottest: true, read: readCoreUsages} },
+	SourceMemory:  func() Source { return &memSource{} },
+	SourceDisk:    func() Source { return newRateSource(SourceDisk, diskFullScale, readDiskBytes) },
+	SourceNetwork: func() Source { return newRateSource(SourceNetwork, netFullScale, readNetBytes) },
+	SourceLoad:    func() Source { return &loadSource{} },
+}
+
+// 来源的显示名称
+var sourceLabels = map[string]string{
+	SourceCPU:        "CPU",
+	SourceCPUMax:     "Hottest Core",
+	SourceMemory:     "Memory",
+	SourceDisk:       "Disk",
+	SourceNetwork:    "Network",
+	SourceLoad:       "Load",
+	SourceExpression: "Expression",
+}
+
+// SourceNames 返回所有内置来源名称
+func SourceNames() []string {
+	return []string{SourceCPU, SourceCPUMax, SourceMemory, SourceDisk, SourceNetwork, SourceLoad}
+}
+
+// SourceLabel 返回来源的显示名称
+func SourceLabel(name string) string {
+	if label, ok := sourceLabels[name]; ok {
+		return label
+	}
+	return name
+}
+
+// NewSource 根据名称创建内置来源
+func NewSource(name string) (Source, error) {
+	factory, ok := sourceFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown monitor source: %s", name)
+	}
+	return factory(), nil
+}
+
+// 将值限制在0-100之间，NaN记为0
+func clampPercent(v float64) float64 {
+	if math.IsNaN(v) || v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// 格式化原始值
+func formatRaw(raw float64, unit string) string {
+	switch unit {
+	case "%":
+		return fmt.Sprintf("%.1f%%", raw)
+	case "B/s":
+		units := []string{"B/s", "KB/s", "MB/s", "GB/s"}
+		i := 0
+		for raw >= 1024 && i < len(units)-1 {
+			raw /= 1024
+			i++
+		}
+		return fmt.Sprintf("%.1f %s", raw, units[i])
+	case "":
+		return fmt.Sprintf("%.2f", raw)
+	default:
+		return fmt.Sprintf("%.1f %s", raw, unit)
+	}
+}
+
+// cpuSource CPU使用率，按核心采样
+type cpuSource struct {
+	// 为true时取最繁忙核心的使用率，否则取所有核心的平均值
+	hottest bool
+	// 读取每核心使用率
+	read func() ([]float64, error)
+}
+
+func (s *cpuSource) Name() string {
+	if s.hottest {
+		return SourceCPUMax
+	}
+	return SourceCPU
+}
+
+func (s *cpuSource) Unit() string { return "%" }
+
+func (s *cpuSource) Sample() (Sample, error) {
+	cores, err := s.read()
+	if err != nil {
+		return Sample{}, err
+	}
+	if len(cores) == 0 {
+		return Sample{}, errors.New("no cpu usage reported")
+	}
+
+	var sum, hottest float64
+	for _, c := range cores {
+		sum += c
+		hottest = max(hottest, c)
+	}
+
+	usage := sum / float64(len(cores))
+	if s.hottest {
+		usage = hottest
+	}
+	return Sample{Source: s.Name(), Unit: s.Unit(), Value: usage, Raw: usage, Cores: cores}, nil
+}
+
+// 每核心使用率的共享读数
+// cpu.Percent 以上一次调用为参照，同一时刻多个CPU来源采样时需要复用同一次读数
+var coreReading struct {
+	sync.Mutex
+	cores []float64
+	time  time.Time
+}
+
+// 在该时间内重复采样时复用上一次读数
+const coreReadingTTL = 100 * time.Millisecond
+
+// 读取每个核心自上次调用以来的使用率，测试时替换
+var cpuPercent = func() ([]float64, error) {
+	return cpu.Percent(0, true)
+}
+
+// 读取每个核心的CPU使用率
+func readCoreUsages() ([]float64, error) {
+	coreReading.Lock()
+	defer coreReading.Unlock()
+
+	if time.Since(coreReading.time) < coreReadingTTL {
+		return coreReading.cores, nil
+	}
+
+	percentages, err := cpuPercent()
+	if err != nil {
+		return nil, err
+	}
+
+	cores := make([]float64, len(percentages))
+	for i, p := range percentages {
+		cores[i] = clampPercent(p)
+	}
+	coreReading.cores = cores
+	coreReading.time = time.Now()
+	return cores, nil
+}
+
+// memSource 内存使用率
+type memSource struct{}
+
+func (s *memSource) Name() string { return SourceMemory }
+
+func (s *memSource) Unit() string { return "%" }
+
+func (s *memSource) Sample() (Sample, error) {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return Sample{}, err
+	}
+	usage := clampPercent(vm.UsedPercent)
+	return Sample{Source: SourceMemory, Unit: s.Unit(), Value: usage, Raw: usage}, nil
+}
+
+// loadSource 1分钟平均负载，按逻辑核心数归一化
+type loadSource struct{}
+
+func (s *loadSource) Name() string { return SourceLoad }
+
+func (s *loadSource) Unit() string { return "" }
+
+func (s *loadSource) Sample() (Sample, error) {
+	avg, err := load.Avg()
+	if err != nil {
+		return Sample{}, err
+	}
+	cores, err := cpu.Counts(true)
+	if err != nil || cores <= 0 {
+		cores = 1
+	}
+	return Sample{
+		Source: SourceLoad,
+		Unit:   s.Unit(),
+		Value:  clampPercent(avg.Load1 / float64(cores) * 100),
+		Raw:    avg.Load1,
+	}, nil
+}
+
+// rateSource 根据累计字节数计算吞吐量
+type rateSource struct {
+	name      string
+	fullScale float64
+	read      func() (uint64, error)
+	now       func() time.Time
+
+	lastBytes uint64
+	lastTime  time.Time
+}
+
+// 创建吞吐量来源，read返回累计字节数
+func newRateSource(name string, fullScale float64, read func() (uint64, error)) *rateSource {
+	return &rateSource{name: name, fullScale: fullScale, read: read, now: time.Now}
+}
+
+func (s *rateSource) Name() string { return s.name }
+
+func (s *rateSource) Unit() string { return "B/s" }
+
+func (s *rateSource) Sample() (Sample, error) {
+	total, err := s.read()
+	if err != nil {
+		return Sample{}, err
+	}
+	now := s.now()
+
+	// 第一次采样没有参照值，计数器重置（例如网卡重新连接）时同样记为0，吞吐量记为0
+	var rate float64
+	if !s.lastTime.IsZero() && total >= s.lastBytes {
+		if elapsed := now.Sub(s.lastTime).Seconds(); elapsed > 0 {
+			rate = float64(total-s.lastBytes) / elapsed
+		}
+	}
+	s.lastBytes = total
+	s.lastTime = now
+
+	return Sample{
+		Source: s.name,
+		Unit:   s.Unit(),
+		Value:  clampPercent(rate / s.fullScale * 100),
+		Raw:    rate,
+	}, nil
+}
+
+// 读取所有磁盘的累计读写字节数
+func readDiskBytes() (uint64, error) {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, c := range counters {
+		total += c.ReadBytes + c.WriteBytes
+	}
+	return total, nil
+}
+
+// 读取所有网卡的累计收发字节数
+func readNetBytes() (uint64, error) {
+	counters, err := net.IOCounters(false)
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, c := range counters {
+		total += c.BytesSent + c.BytesRecv
+	}
+	return total, nil
+}
@@ -0,0 +1,189 @@
+package monitor
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// 返回固定每核心使用率的读取函数
+func fakeCores(cores ...float64) func() ([]float64, error) {
+	return func() ([]float64, error) { return cores, nil }
+}
+
+func TestCPUSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		hottest bool
+		cores   []float64
+		want    float64
+	}{
+		{"average", false, []float64{10, 20, 30, 80}, 35},
+		{"hottest core", true, []float64{10, 20, 30, 80}, 80},
+		{"single core", true, []float64{42}, 42},
+		{"idle", false, []float64{0, 0}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &cpuSource{hottest: tt.hottest, read: fakeCores(tt.cores...)}
+			sample, err := s.Sample()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sample.Value != tt.want || sample.Raw != tt.want {
+				t.Errorf("value %v raw %v, want %v", sample.Value, sample.Raw, tt.want)
+			}
+			if !slices.Equal(sample.Cores, tt.cores) {
+				t.Errorf("cores %v, want %v", sample.Cores, tt.cores)
+			}
+			if want := map[bool]string{false: SourceCPU, true: SourceCPUMax}[tt.hottest]; sample.Source != want {
+				t.Errorf("source %q, want %q", sample.Source, want)
+			}
+		})
+	}
+
+	if _, err := (&cpuSource{read: fakeCores()}).Sample(); err == nil {
+		t.Error("expected an error without cores")
+	}
+	failing := &cpuSource{read: func() ([]float64, error) { return nil, errors.New("boom") }}
+	if _, err := failing.Sample(); err == nil {
+		t.Error("expected the read error")
+	}
+}
+
+func TestReadCoreUsages(t *testing.T) {
+	calls := 0
+	orig := cpuPercent
+	cpuPercent = func() ([]float64, error) {
+		calls++
+		return []float64{-5, 50, 150}, nil
+	}
+	t.Cleanup(func() {
+		cpuPercent = orig
+		coreReading.time = time.Time{}
+	})
+	coreReading.time = time.Time{}
+
+	cores, err := readCoreUsages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{0, 50, 100}; !slices.Equal(cores, want) {
+		t.Errorf("cores %v, want clamped %v", cores, want)
+	}
+
+	// 同一时刻的多个CPU来源复用同一次读数
+	if _, err := readCoreUsages(); err != nil || calls != 1 {
+		t.Errorf("read %d times, want 1 (err %v)", calls, err)
+	}
+}
+
+func TestCoreUsages(t *testing.T) {
+	cores := []float64{10, 90}
+	sample := Sample{Source: SourceExpression, Parts: []Sample{
+		{Source: SourceMemory},
+		{Source: SourceCPUMax, Cores: cores},
+	}}
+	if got := sample.CoreUsages(); !slices.Equal(got, cores) {
+		t.Errorf("CoreUsages %v, want %v", got, cores)
+	}
+	if got := (Sample{Source: SourceMemory}).CoreUsages(); got != nil {
+		t.Errorf("CoreUsages %v, want nil", got)
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	for _, name := range SourceNames() {
+		s, err := NewSource(name)
+		if err != nil {
+			t.Fatalf("NewSource(%q): %v", name, err)
+		}
+		if s.Name() != name {
+			t.Errorf("NewSource(%q) returned source %q", name, s.Name())
+		}
+		if SourceLabel(name) == name {
+			t.Errorf("source %q has no label", name)
+		}
+	}
+
+	// 每次创建新的实例，吞吐量来源不共享参照值
+	a, _ := NewSource(SourceDisk)
+	b, _ := NewSource(SourceDisk)
+	if a == b {
+		t.Error("NewSource returned a shared rate source")
+	}
+
+	for _, name := range []string{"", "gpu", "CPU", SourceExpression} {
+		if _, err := NewSource(name); err == nil {
+			t.Errorf("NewSource(%q) accepted an unknown source", name)
+		}
+	}
+}
+
+func TestRateSource(t *testing.T) {
+	start := time.Unix(1000, 0)
+	steps := []struct {
+		name    string
+		bytes   uint64
+		elapsed time.Duration
+		rate    float64
+		value   float64
+	}{
+		{"first sample", 5000, 0, 0, 0},
+		{"steady rate", 5000 + 1000, time.Second, 1000, 10},
+		{"half second", 6000 + 1000, 500 * time.Millisecond, 2000, 20},
+		{"zero elapsed", 7000 + 500, 0, 0, 0},
+		{"counter reset", 100, time.Second, 0, 0},
+		{"after reset", 100 + 300, 2 * time.Second, 150, 1.5},
+		{"above full scale", 400 + 100000, time.Second, 100000, 100},
+	}
+
+	var bytes uint64
+	now := start
+	s := &rateSource{
+		name:      SourceNetwork,
+		fullScale: 10000,
+		read:      func() (uint64, error) { return bytes, nil },
+		now:       func() time.Time { return now },
+	}
+	for _, step := range steps {
+		bytes = step.bytes
+		now = now.Add(step.elapsed)
+		sample, err := s.Sample()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if sample.Raw != step.rate || sample.Value != step.value {
+			t.Errorf("%s: raw %v value %v, want %v and %v", step.name, sample.Raw, sample.Value, step.rate, step.value)
+		}
+		if sample.Source != SourceNetwork || sample.Unit != "B/s" {
+			t.Errorf("%s: unexpected sample %+v", step.name, sample)
+		}
+	}
+
+	failing := &rateSource{read: func() (uint64, error) { return 0, errors.New("boom") }, now: time.Now}
+	if _, err := failing.Sample(); err == nil {
+		t.Error("expected the read error")
+	}
+}
+
+func TestFormatRaw(t *testing.T) {
+	tests := []struct {
+		raw  float64
+		unit string
+		want string
+	}{
+		{42.25, "%", "42.2%"},
+		{512, "B/s", "512.0 B/s"},
+		{1536, "B/s", "1.5 KB/s"},
+		{3 * 1024 * 1024, "B/s", "3.0 MB/s"},
+		{1.234, "", "1.23"},
+		{7, "rpm", "7.0 rpm"},
+	}
+	for _, tt := range tests {
+		if got := formatRaw(tt.raw, tt.unit); got != tt.want {
+			t.Errorf("formatRaw(%v, %q) = %q, want %q", tt.raw, tt.unit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package overlay
+
+// 字形的宽度和高度，单位为像素
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+// 内置的3×5点阵数字字体，'#'表示点亮的像素
+var digitFont = [10][glyphHeight]string{
+	{"###", "#.#", "#.#", "#.#", "###"},
+	{".#.", "##.", ".#.", ".#.", "###"},
+	{"###", "..#", "###", "#..", "###"},
+	{"###", "..#", "###", "..#", "###"},
+	{"#.#", "#.#", "###", "..#", "..#"},
+	{"###", "#..", "###", "..#", "###"},
+	{"###", "#..", "###", "#.#", "###"},
+	{"###", "..#", ".#.", ".#.", ".#."},
+	{"###", "#.#", "###", "#.#", "###"},
+	{"###", "#.#", "###", "..#", "###"},
+}
+
+// 字形在(x, y)处的像素是否点亮
+func glyphPixel(digit byte, x, y int) bool {
+	return digitFont[digit-'0'][y][x] == '#'
+}
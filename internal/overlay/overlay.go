@@ -0,0 +1,140 @@
+package overlay
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+)
+
+// Mode 叠加指标的显示方式
+type Mode string
+
+const (
+	// ModeOff 不叠加
+	ModeOff Mode = "off"
+	// ModeDigits 在右下角显示数字
+	ModeDigits Mode = "digits"
+	// ModeBar 在底部显示进度条
+	ModeBar Mode = "bar"
+)
+
+// Modes 所有显示方式，按菜单顺序排列
+var Modes = []Mode{ModeOff, ModeDigits, ModeBar}
+
+// 菜单中显示的名称
+var modeLabels = map[Mode]string{
+	ModeOff:    "Off",
+	ModeDigits: "Digits",
+	ModeBar:    "Bar",
+}
+
+// Label 返回显示方式在菜单中的名称
+func (m Mode) Label() string {
+	return modeLabels[m]
+}
+
+// ParseMode 解析显示方式，空字符串视为ModeOff
+func ParseMode(s string) (Mode, error) {
+	if s == "" {
+		return ModeOff, nil
+	}
+	for _, m := range Modes {
+		if Mode(s) == m {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown overlay %q (allowed: off, digits, bar)", s)
+}
+
+var (
+	// 数字的颜色
+	textColor = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	// 数字和进度条的半透明底色，保证在任意图标上都能看清
+	backdropColor = color.NRGBA{A: 0xb0}
+	// 进度条按数值由低到高使用的颜色
+	barColors = []color.NRGBA{
+		{R: 0x4c, G: 0xd9, B: 0x64, A: 0xff},
+		{R: 0xff, G: 0xcc, B: 0x00, A: 0xff},
+		{R: 0xff, G: 0x3b, B: 0x30, A: 0xff},
+	}
+)
+
+// Render 在图像上叠加数值 (0-100)，返回新的图像，不修改img
+func Render(img image.Image, value float64, mode Mode) *image.NRGBA {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	value = math.Max(0, math.Min(100, value))
+	switch mode {
+	case ModeDigits:
+		drawDigits(dst, strconv.Itoa(int(math.Round(value))))
+	case ModeBar:
+		drawBar(dst, value)
+	}
+	return dst
+}
+
+// 在右下角绘制数字，字形按图标高度整数倍放大
+func drawDigits(dst *image.NRGBA, text string) {
+	size := dst.Bounds().Size()
+	scale := max(1, size.Y/16)
+	width := (len(text)*(glyphWidth+1) - 1) * scale
+	height := glyphHeight * scale
+
+	// 底色比文字多出一个像素的边距
+	origin := image.Pt(size.X-width-scale, size.Y-height-scale)
+	backdrop := image.Rect(origin.X-scale, origin.Y-scale, size.X, size.Y)
+	draw.Draw(dst, backdrop, image.NewUniform(backdropColor), image.Point{}, draw.Over)
+
+	for i := range len(text) {
+		left := origin.X + i*(glyphWidth+1)*scale
+		for y := range glyphHeight {
+			for x := range glyphWidth {
+				if !glyphPixel(text[i], x, y) {
+					continue
+				}
+				pixel := image.Rect(left+x*scale, origin.Y+y*scale, left+(x+1)*scale, origin.Y+(y+1)*scale)
+				draw.Draw(dst, pixel, image.NewUniform(textColor), image.Point{}, draw.Src)
+			}
+		}
+	}
+}
+
+// 在底部绘制进度条，颜色随数值变化
+func drawBar(dst *image.NRGBA, value float64) {
+	size := dst.Bounds().Size()
+	height := max(2, size.Y/8)
+	track := image.Rect(0, size.Y-height, size.X, size.Y)
+	draw.Draw(dst, track, image.NewUniform(backdropColor), image.Point{}, draw.Over)
+
+	fill := track
+	fill.Max.X = int(math.Round(float64(size.X) * value / 100))
+	level := min(len(barColors)-1, int(value/100*float64(len(barColors))))
+	draw.Draw(dst, fill, image.NewUniform(barColors[level]), image.Point{}, draw.Src)
+}
+
+// RenderIcon 解码一帧PNG或ICO图标，叠加数值后按原格式重新编码
+func RenderIcon(frame []byte, value float64, mode Mode) ([]byte, error) {
+	img, err := resource.DecodeIcon(frame)
+	if err != nil {
+		return nil, err
+	}
+	rendered := Render(img, value, mode)
+
+	if !bytes.HasPrefix(frame, []byte("\x89PNG")) {
+		return resource.EncodeICO(rendered)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, rendered); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
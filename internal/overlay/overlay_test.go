@@ -0,0 +1,200 @@
+package overlay
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"testing"
+
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+)
+
+// 填充纯色图像
+func solid(size int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := range size {
+		for x := range size {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// 统计与原图不同的像素
+func changed(a, b image.Image) (n int, bounds image.Rectangle) {
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if a.At(x, y) != b.At(x, y) {
+				n++
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return n, bounds
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Mode
+		err  bool
+	}{
+		{"", ModeOff, false},
+		{"off", ModeOff, false},
+		{"digits", ModeDigits, false},
+		{"bar", ModeBar, false},
+		{"graph", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	src := solid(16, color.NRGBA{R: 0x20, G: 0x40, B: 0x60, A: 0xff})
+
+	if n, _ := changed(src, Render(src, 42, ModeOff)); n != 0 {
+		t.Errorf("ModeOff changed %d pixels", n)
+	}
+
+	// 数字绘制在右下角，不修改原图
+	digits := Render(src, 42, ModeDigits)
+	n, bounds := changed(src, digits)
+	if n == 0 || bounds.Max != image.Pt(16, 16) || bounds.Min.X < 4 || bounds.Min.Y < 8 {
+		t.Errorf("digits changed %d pixels in %v", n, bounds)
+	}
+	if src.At(15, 15) != (color.NRGBA{R: 0x20, G: 0x40, B: 0x60, A: 0xff}) {
+		t.Error("Render modified the source image")
+	}
+	// 不同数值绘制不同的字形
+	if n, _ := changed(digits, Render(src, 17, ModeDigits)); n == 0 {
+		t.Error("42 and 17 render the same pixels")
+	}
+
+	// 进度条的长度与数值成正比
+	for _, tt := range []struct {
+		value float64
+		want  int
+	}{{0, 0}, {25, 4}, {50, 8}, {100, 16}, {150, 16}} {
+		bar := Render(src, tt.value, ModeBar)
+		filled := 0
+		for x := range 16 {
+			if bar.NRGBAAt(x, 15).A == 0xff && bar.NRGBAAt(x, 15) != Render(src, 0, ModeBar).NRGBAAt(x, 15) {
+				filled++
+			}
+		}
+		if filled != tt.want {
+			t.Errorf("bar at %v filled %d pixels, want %d", tt.value, filled, tt.want)
+		}
+	}
+}
+
+func TestRenderIcon(t *testing.T) {
+	src := solid(32, color.NRGBA{B: 0xff, A: 0xff})
+
+	var pngFrame bytes.Buffer
+	_ = png.Encode(&pngFrame, src)
+	icoFrame, err := resource.EncodeICO(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, frame := range map[string][]byte{"png": pngFrame.Bytes(), "ico": icoFrame} {
+		out, err := RenderIcon(frame, 88, ModeDigits)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// 保持原来的格式
+		if bytes.HasPrefix(out, []byte("\x89PNG")) != (name == "png") {
+			t.Errorf("%s: output format changed", name)
+		}
+		img, err := resource.DecodeIcon(out)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", name, err)
+		}
+		if img.Bounds().Size() != image.Pt(32, 32) {
+			t.Errorf("%s: size %v", name, img.Bounds().Size())
+		}
+		if n, _ := changed(src, img); n == 0 {
+			t.Errorf("%s: overlay was not drawn", name)
+		}
+	}
+
+	if _, err := RenderIcon([]byte("not an icon"), 1, ModeBar); err == nil {
+		t.Error("RenderIcon accepted invalid data")
+	}
+}
+
+// recordSink 记录收到的帧
+type recordSink struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func (r *recordSink) ShowFrame(frame []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, frame)
+}
+
+func (r *recordSink) last() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.frames[len(r.frames)-1]
+}
+
+func TestSink(t *testing.T) {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, solid(16, color.NRGBA{G: 0xff, A: 0xff}))
+	frame := buf.Bytes()
+
+	rec := &recordSink{}
+	s := NewSink(rec)
+
+	// 默认原样输出
+	s.ShowFrame(frame)
+	if !bytes.Equal(rec.last(), frame) {
+		t.Fatal("ModeOff modified the frame")
+	}
+
+	s.SetMode(ModeDigits)
+	s.SetValue(12.4)
+	s.ShowFrame(frame)
+	first := rec.last()
+	if bytes.Equal(first, frame) {
+		t.Fatal("overlay was not applied")
+	}
+
+	// 取整后相同的数值使用缓存
+	s.SetValue(11.6)
+	s.ShowFrame(frame)
+	if &rec.last()[0] != &first[0] {
+		t.Error("frame was rendered again for the same value")
+	}
+
+	// 数值变化后重新绘制
+	s.SetValue(50)
+	s.ShowFrame(frame)
+	if bytes.Equal(rec.last(), first) {
+		t.Error("frame was not rendered again for a new value")
+	}
+
+	// 无法解码的帧原样输出
+	junk := []byte("junk")
+	s.ShowFrame(junk)
+	if !bytes.Equal(rec.last(), junk) {
+		t.Error("invalid frame was not passed through")
+	}
+
+	s.SetMode(ModeOff)
+	s.ShowFrame(frame)
+	if !bytes.Equal(rec.last(), frame) {
+		t.Error("ModeOff after overlay modified the frame")
+	}
+}
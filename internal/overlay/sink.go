@@ -0,0 +1,91 @@
+package overlay
+
+import (
+	"log"
+	"math"
+	"sync"
+
+	"github.com/eatmoreapple/go-runcat/internal/animation"
+)
+
+// Sink 在每一帧上叠加当前数值后交给下一个输出
+// 叠加后的帧按原始帧缓存，数值或显示方式变化时才重新绘制
+type Sink struct {
+	// 下一个输出
+	next animation.Sink
+
+	// 保护以下状态的互斥锁
+	mu sync.Mutex
+	// 当前显示方式
+	mode Mode
+	// 当前显示的数值，取整后比较，避免细微变化导致重新绘制
+	value int
+	// 原始帧到叠加后帧的缓存，只对当前数值和显示方式有效
+	cache map[*byte][]byte
+}
+
+// NewSink 创建叠加输出，默认不叠加
+func NewSink(next animation.Sink) *Sink {
+	return &Sink{
+		next:  next,
+		mode:  ModeOff,
+		cache: make(map[*byte][]byte),
+	}
+}
+
+// SetMode 设置显示方式
+func (s *Sink) SetMode(mode Mode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mode != mode {
+		s.mode = mode
+		clear(s.cache)
+	}
+}
+
+// SetValue 设置显示的数值 (0-100)
+func (s *Sink) SetValue(value float64) {
+	rounded := int(math.Round(math.Max(0, math.Min(100, value))))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.value != rounded {
+		s.value = rounded
+		clear(s.cache)
+	}
+}
+
+// ShowFrame 叠加数值后输出帧，绘制失败时输出原始帧
+func (s *Sink) ShowFrame(frame []byte) {
+	s.mu.Lock()
+	mode, value := s.mode, s.value
+	var cached []byte
+	if len(frame) > 0 {
+		cached = s.cache[&frame[0]]
+	}
+	s.mu.Unlock()
+
+	if mode == ModeOff || len(frame) == 0 {
+		s.next.ShowFrame(frame)
+		return
+	}
+	if cached != nil {
+		s.next.ShowFrame(cached)
+		return
+	}
+
+	rendered, err := RenderIcon(frame, float64(value), mode)
+	if err != nil {
+		log.Printf("Failed to render overlay: %v", err)
+		rendered = frame
+	}
+
+	s.mu.Lock()
+	// 绘制期间设置已变化时不缓存
+	if s.mode == mode && s.value == value {
+		s.cache[&frame[0]] = rendered
+	}
+	s.mu.Unlock()
+
+	s.next.ShowFrame(rendered)
+}
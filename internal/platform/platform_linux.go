@@ -0,0 +1,259 @@
+//go:build linux
+
+package platform
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 支持的任务管理器，按优先级排列
+var taskManagers = []string{
+	"gnome-system-monitor",
+	"ksysguard",
+	"plasma-systemmonitor",
+	"xfce4-taskmanager",
+}
+
+type linuxPlatform struct{}
+
+func newPlatform() Platform {
+	return &linuxPlatform{}
+}
+
+// GetSystemTheme 获取Linux系统主题 (light/dark)
+func (p *linuxPlatform) GetSystemTheme() string {
+	// 优先读取 freedesktop 的 org.freedesktop.appearance 设置
+	if theme, ok := p.portalColorScheme(); ok {
+		return theme
+	}
+
+	// 其次读取 GNOME 的 color-scheme 设置
+	if output, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "color-scheme").Output(); err == nil {
+		switch strings.Trim(strings.TrimSpace(string(output)), "'") {
+		case "prefer-dark":
+			return "dark"
+		case "prefer-light":
+			return "light"
+		}
+	}
+
+	// 最后根据 GTK 主题名称判断
+	if output, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "gtk-theme").Output(); err == nil {
+		if strings.Contains(strings.ToLower(string(output)), "dark") {
+			return "dark"
+		}
+	}
+	return "light"
+}
+
+// portalColorScheme 通过 xdg-desktop-portal 读取 org.freedesktop.appearance color-scheme
+// 0: 无偏好, 1: 偏好深色, 2: 偏好浅色
+func (p *linuxPlatform) portalColorScheme() (string, bool) {
+	cmd := exec.Command("gdbus", "call", "--session",
+		"--dest", "org.freedesktop.portal.Desktop",
+		"--object-path", "/org/freedesktop/portal/desktop",
+		"--method", "org.freedesktop.portal.Settings.Read",
+		"org.freedesktop.appearance", "color-scheme")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", false
+	}
+
+	// 输出格式类似 (<<uint32 1>>,)
+	switch {
+	case strings.Contains(string(output), "uint32 1"):
+		return "dark", true
+	case strings.Contains(string(output), "uint32 2"):
+		return "light", true
+	}
+	return "", false
+}
+
+// WatchSystemTheme 通过 gsettings monitor 和 xdg-desktop-portal 的 SettingChanged 信号监听Linux系统主题变化
+// 两者都不可用或都已退出时退化为轮询
+func (p *linuxPlatform) WatchSystemTheme(ctx context.Context) <-chan string {
+	changed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	gsettings := startThemeMonitor(ctx, &wg, changed, isGSettingsThemeLine,
+		"gsettings", "monitor", "org.gnome.desktop.interface")
+	portal := startThemeMonitor(ctx, &wg, changed, isPortalThemeLine,
+		"gdbus", "monitor", "--session",
+		"--dest", "org.freedesktop.portal.Desktop",
+		"--object-path", "/org/freedesktop/portal/desktop")
+	if !gsettings && !portal {
+		return pollSystemTheme(ctx, p, DefaultThemePollInterval)
+	}
+	// 所有监听命令退出后结束
+	go func() {
+		wg.Wait()
+		close(changed)
+	}()
+
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+
+		last := p.GetSystemTheme()
+		for range changed {
+			current := p.GetSystemTheme()
+			if current == last {
+				continue
+			}
+			last = current
+			select {
+			case ch <- current:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// 监听命令提前退出时改为轮询
+		for current := range pollSystemTheme(ctx, p, DefaultThemePollInterval) {
+			select {
+			case ch <- current:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// startThemeMonitor 启动监听命令，输出中有满足match的行时通知changed，命令无法启动时返回false
+func startThemeMonitor(ctx context.Context, wg *sync.WaitGroup, changed chan<- struct{}, match func(line string) bool, name string, args ...string) bool {
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false
+	}
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() { _ = cmd.Wait() }()
+
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if !match(scanner.Text()) {
+				continue
+			}
+			// 已有未处理的通知时合并
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return true
+}
+
+// isGSettingsThemeLine 判断 gsettings monitor 的输出是否为主题相关的键
+func isGSettingsThemeLine(line string) bool {
+	return strings.HasPrefix(line, "color-scheme") || strings.HasPrefix(line, "gtk-theme")
+}
+
+// isPortalThemeLine 判断 gdbus monitor 的输出是否为 color-scheme 的 SettingChanged 信号，例如
+// /org/freedesktop/portal/desktop: org.freedesktop.portal.Settings.SettingChanged ('org.freedesktop.appearance', 'color-scheme', <uint32 1>)
+func isPortalThemeLine(line string) bool {
+	return strings.Contains(line, "SettingChanged") &&
+		strings.Contains(line, "org.freedesktop.appearance") &&
+		strings.Contains(line, "color-scheme")
+}
+
+// autostartPath 返回XDG自启动文件路径
+func (p *linuxPlatform) autostartPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "autostart", "go-runcat.desktop"), nil
+}
+
+// SetStartup 设置Linux开机自启动
+func (p *linuxPlatform) SetStartup(enable bool) error {
+	desktopPath, err := p.autostartPath()
+	if err != nil {
+		return err
+	}
+
+	if !enable {
+		if err := os.Remove(desktopPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// 获取应用程序路径
+	execPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// 确保目录存在
+	if err := os.MkdirAll(filepath.Dir(desktopPath), 0755); err != nil {
+		return err
+	}
+
+	// 创建desktop文件内容
+	desktopContent := `[Desktop Entry]
+Type=Application
+Name=GoRunCat
+Comment=A running cat on your system tray
+Exec=` + quoteExecArg(execPath) + `
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`
+	return os.WriteFile(desktopPath, []byte(desktopContent), 0644)
+}
+
+// Desktop Entry中Exec的引号内需要转义的字符，%需要写成%%
+var execArgReplacer = strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`, `%`, `%%`)
+
+// Desktop Entry中字符串值的转义，在引号规则之前处理
+var desktopStringReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+// quoteExecArg 将路径转换为Exec中带引号的参数
+// 反斜杠会先按引号规则、再按字符串规则转义，例如字面的\最终写为\\\\
+func quoteExecArg(arg string) string {
+	return desktopStringReplacer.Replace(`"` + execArgReplacer.Replace(arg) + `"`)
+}
+
+// IsStartupEnabled 检查Linux是否已设置开机自启动
+func (p *linuxPlatform) IsStartupEnabled() (bool, error) {
+	desktopPath, err := p.autostartPath()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(desktopPath)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// OpenTaskManager 打开Linux系统监视器
+func (p *linuxPlatform) OpenTaskManager() error {
+	for _, name := range taskManagers {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path)
+		return cmd.Start()
+	}
+	return errors.New("no supported task manager found")
+}
@@ -0,0 +1,63 @@
+package platform
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQuoteExecArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{`/usr/bin/go-runcat`, `"/usr/bin/go-runcat"`},
+		{`/opt/Run Cat/go-runcat`, `"/opt/Run Cat/go-runcat"`},
+		{`/opt/"cat"/run`, `"/opt/\\"cat\\"/run"`},
+		{"/opt/`cat`/run", "\"/opt/\\\\`cat\\\\`/run\""},
+		{`/home/$USER/run`, `"/home/\\$USER/run"`},
+		{`/opt/back\slash/run`, `"/opt/back\\\\slash/run"`},
+		{`/opt/100%/run`, `"/opt/100%%/run"`},
+		{"/opt/line\nbreak", `"/opt/line\nbreak"`},
+	}
+	for _, tt := range tests {
+		if got := quoteExecArg(tt.arg); got != tt.want {
+			t.Errorf("quoteExecArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestSetStartup(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	p := &linuxPlatform{}
+
+	if err := p.SetStartup(true); err != nil {
+		t.Fatalf("SetStartup(true): %v", err)
+	}
+	if enabled, err := p.IsStartupEnabled(); err != nil || !enabled {
+		t.Fatalf("IsStartupEnabled() = %v, %v after enabling", enabled, err)
+	}
+
+	path, err := p.autostartPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	execPath, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\nExec=" + quoteExecArg(execPath) + "\n"; !strings.Contains(string(data), want) {
+		t.Errorf("desktop entry\n%s\ndoes not contain %q", data, want)
+	}
+
+	if err := p.SetStartup(false); err != nil {
+		t.Fatalf("SetStartup(false): %v", err)
+	}
+	if enabled, err := p.IsStartupEnabled(); err != nil || enabled {
+		t.Fatalf("IsStartupEnabled() = %v, %v after disabling", enabled, err)
+	}
+}
@@ -0,0 +1,60 @@
+package platform
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultThemePollInterval 不支持主动推送的平台轮询系统主题的间隔
+const DefaultThemePollInterval = 5 * time.Second
+
+// ThemeWatcher 可选接口，由能够主动推送系统主题变化的平台实现
+type ThemeWatcher interface {
+	// WatchSystemTheme 监听系统主题变化，每次变化时发送新主题 (light/dark)
+	// 当ctx取消时关闭返回的通道
+	WatchSystemTheme(ctx context.Context) <-chan string
+}
+
+// WatchSystemTheme 监听系统主题变化
+// 平台实现了ThemeWatcher时使用其推送，否则按interval轮询GetSystemTheme
+func WatchSystemTheme(ctx context.Context, p Platform, interval time.Duration) <-chan string {
+	if w, ok := p.(ThemeWatcher); ok {
+		return w.WatchSystemTheme(ctx)
+	}
+	return pollSystemTheme(ctx, p, interval)
+}
+
+// pollSystemTheme 轮询系统主题，仅在主题变化时发送
+func pollSystemTheme(ctx context.Context, p Platform, interval time.Duration) <-chan string {
+	if interval <= 0 {
+		interval = DefaultThemePollInterval
+	}
+
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := p.GetSystemTheme()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current := p.GetSystemTheme()
+				if current == last {
+					continue
+				}
+				last = current
+				select {
+				case ch <- current:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,118 @@
+package platform
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePlatform 主题可在测试中修改的平台实现
+type fakePlatform struct {
+	mu    sync.Mutex
+	theme string
+	// GetSystemTheme 的调用次数
+	reads int
+}
+
+func (p *fakePlatform) GetSystemTheme() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reads++
+	return p.theme
+}
+
+func (p *fakePlatform) setTheme(theme string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.theme = theme
+}
+
+// 等待再被轮询n次，确保之前的修改已被读取
+func (p *fakePlatform) waitReads(t *testing.T, n int) {
+	t.Helper()
+	p.mu.Lock()
+	target := p.reads + n
+	p.mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.mu.Lock()
+		reads := p.reads
+		p.mu.Unlock()
+		if reads >= target {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("theme was read %d times, want %d", reads, target)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (p *fakePlatform) SetStartup(bool) error           { return nil }
+func (p *fakePlatform) IsStartupEnabled() (bool, error) { return false, nil }
+func (p *fakePlatform) OpenTaskManager() error          { return nil }
+
+// fakeWatcher 主动推送主题变化的平台实现
+type fakeWatcher struct {
+	fakePlatform
+	ch chan string
+}
+
+func (p *fakeWatcher) WatchSystemTheme(context.Context) <-chan string { return p.ch }
+
+func TestPollSystemTheme(t *testing.T) {
+	p := &fakePlatform{theme: "light"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := WatchSystemTheme(ctx, p, time.Millisecond)
+
+	// 主题不变时不发送
+	p.waitReads(t, 3)
+	expectNone(t, ch)
+
+	for _, theme := range []string{"dark", "light"} {
+		p.setTheme(theme)
+		select {
+		case got := <-ch:
+			if got != theme {
+				t.Fatalf("received %q, want %q", got, theme)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("change to %q was not emitted", theme)
+		}
+
+		// 每次变化只发送一次
+		p.waitReads(t, 3)
+		expectNone(t, ch)
+	}
+
+	cancel()
+	select {
+	case theme, ok := <-ch:
+		if ok {
+			t.Fatalf("received %q after cancel, want closed channel", theme)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed after cancel")
+	}
+}
+
+func TestWatchSystemThemeUsesWatcher(t *testing.T) {
+	p := &fakeWatcher{ch: make(chan string)}
+	if ch := WatchSystemTheme(context.Background(), p, time.Millisecond); ch != (<-chan string)(p.ch) {
+		t.Fatal("WatchSystemTheme polled a platform that implements ThemeWatcher")
+	}
+}
+
+// 通道中没有待接收的主题
+func expectNone(t *testing.T, ch <-chan string) {
+	t.Helper()
+	select {
+	case theme := <-ch:
+		t.Fatalf("unexpected theme %q", theme)
+	default:
+	}
+}
@@ -0,0 +1,596 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+)
+
+// DefaultFrameSize 导入时生成的PNG帧的边长
+const DefaultFrameSize = 32
+
+// ICO中包含的尺寸，对应托盘在不同缩放比例下的图标大小
+var icoSizes = []int{16, 24, 32}
+
+// 没有指定帧延迟时使用的默认值
+const defaultFrameDelay = 100 * time.Millisecond
+
+// 导入时合成的所有帧的像素总数上限，约占用256 MiB内存
+const maxImportPixels = 1 << 26
+
+// 检查合成count帧width x height的画布所需的像素总数，在分配内存之前调用
+func checkCanvas(width, height, count int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("convert: invalid canvas size %dx%d", width, height)
+	}
+	if uint64(width)*uint64(height)*uint64(max(count, 1)) > maxImportPixels {
+		return fmt.Errorf("convert: %d frames of %dx%d are too large to import", max(count, 1), width, height)
+	}
+	return nil
+}
+
+// Frame 动画中的一帧
+type Frame struct {
+	// 完整合成后的图像
+	Image image.Image
+	// 显示时长，静态精灵图为0
+	Delay time.Duration
+}
+
+// DecodeAnimation 解码动画GIF、APNG或PNG精灵图为完整的帧
+// 普通PNG按layout切分，layout为零值时按正方形帧推断横排或竖排
+func DecodeAnimation(data []byte, layout SpriteLayout) ([]Frame, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return decodeGIF(data)
+	case bytes.HasPrefix(data, pngSignature):
+		if isAPNG(data) {
+			return decodeAPNG(data)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCanvas(config.Width, config.Height, 1); err != nil {
+			return nil, err
+		}
+		sheet, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		images, err := SliceSprite(sheet, layout)
+		if err != nil {
+			return nil, err
+		}
+		frames := make([]Frame, len(images))
+		for i, img := range images {
+			frames[i] = Frame{Image: img}
+		}
+		return frames, nil
+	default:
+		return nil, errors.New("convert: unsupported format, expected GIF, APNG or PNG")
+	}
+}
+
+// 解码GIF，按处置方式逐帧合成
+// 解码之前按画布尺寸和帧数检查合成所需的内存，gif.DecodeAll会一次性分配所有帧
+func decodeGIF(data []byte) ([]Frame, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	count, err := countGIFFrames(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCanvas(config.Width, config.Height, count); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	frames := make([]Frame, len(g.Image))
+	for i, img := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(canvas)
+		}
+
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+
+		delay := defaultFrameDelay
+		if i < len(g.Delay) && g.Delay[i] > 0 {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		frames[i] = Frame{Image: cloneNRGBA(canvas), Delay: delay}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, nil
+}
+
+// 统计GIF中的帧数，只跳过数据块，不解码图像数据
+func countGIFFrames(data []byte) (int, error) {
+	truncated := errors.New("gif: truncated data")
+	// 文件头和逻辑屏幕描述符，之后是可选的全局颜色表
+	if len(data) < 13 {
+		return 0, truncated
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+
+	// 跳过以长度为0的子块结尾的数据子块
+	skipSubBlocks := func() error {
+		for {
+			if pos >= len(data) {
+				return truncated
+			}
+			n := int(data[pos])
+			pos += 1 + n
+			if n == 0 {
+				return nil
+			}
+		}
+	}
+
+	count := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // 扩展块
+			pos += 2
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x2c: // 图像描述符，之后是可选的局部颜色表、LZW最小码长和图像数据
+			if pos+10 > len(data) {
+				return 0, truncated
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+			count++
+		case 0x3b: // 结尾
+			return count, nil
+		default:
+			return 0, fmt.Errorf("gif: unknown block 0x%02x", data[pos])
+		}
+	}
+	return count, nil
+}
+
+// PNG数据块
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// 读取PNG的所有数据块
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("png: invalid signature")
+	}
+	var chunks []pngChunk
+	for rest := data[len(pngSignature):]; len(rest) > 0; {
+		if len(rest) < 12 {
+			return nil, errors.New("png: truncated chunk")
+		}
+		length := binary.BigEndian.Uint32(rest)
+		if uint64(length)+12 > uint64(len(rest)) {
+			return nil, errors.New("png: truncated chunk")
+		}
+		chunks = append(chunks, pngChunk{typ: string(rest[4:8]), data: rest[8 : 8+length]})
+		rest = rest[12+length:]
+	}
+	return chunks, nil
+}
+
+// 检查PNG是否包含动画控制块
+func isAPNG(data []byte) bool {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return false
+	}
+	for _, c := range chunks {
+		switch c.typ {
+		case "acTL":
+			return true
+		case "IDAT":
+			// acTL必须出现在IDAT之前
+			return false
+		}
+	}
+	return false
+}
+
+// APNG帧控制信息
+type apngFrame struct {
+	width, height int
+	x, y          int
+	delay         time.Duration
+	dispose       byte
+	blend         byte
+	data          bytes.Buffer
+}
+
+// APNG处置和混合方式
+const (
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendOver         = 1
+)
+
+// 解码APNG，每帧重新组装为独立的PNG后解码，再按处置和混合方式合成
+func decodeAPNG(data []byte) ([]Frame, error) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) != 13 {
+		return nil, errors.New("apng: missing IHDR")
+	}
+	ihdr := chunks[0].data
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	width, height := config.Width, config.Height
+	if err := checkCanvas(width, height, 1); err != nil {
+		return nil, err
+	}
+
+	// 收集所有帧共享的辅助数据块（调色板、透明度等）
+	var shared []pngChunk
+	var frames []*apngFrame
+	var current *apngFrame
+	for _, c := range chunks[1:] {
+		switch c.typ {
+		case "fcTL":
+			if len(c.data) != 26 {
+				return nil, errors.New("apng: invalid fcTL")
+			}
+			num := binary.BigEndian.Uint16(c.data[20:])
+			den := binary.BigEndian.Uint16(c.data[22:])
+			if den == 0 {
+				den = 100
+			}
+			// 帧必须位于画布之内，按uint64计算避免溢出
+			w, h := binary.BigEndian.Uint32(c.data[4:]), binary.BigEndian.Uint32(c.data[8:])
+			x, y := binary.BigEndian.Uint32(c.data[12:]), binary.BigEndian.Uint32(c.data[16:])
+			if w == 0 || h == 0 || uint64(x)+uint64(w) > uint64(width) || uint64(y)+uint64(h) > uint64(height) {
+				return nil, fmt.Errorf("apng: frame %d (%dx%d at %d,%d) is outside the %dx%d canvas", len(frames), w, h, x, y, width, height)
+			}
+			current = &apngFrame{
+				width:   int(binary.BigEndian.Uint32(c.data[4:])),
+				height:  int(binary.BigEndian.Uint32(c.data[8:])),
+				x:       int(binary.BigEndian.Uint32(c.data[12:])),
+				y:       int(binary.BigEndian.Uint32(c.data[16:])),
+				delay:   time.Duration(num) * time.Second / time.Duration(den),
+				dispose: c.data[24],
+				blend:   c.data[25],
+			}
+			frames = append(frames, current)
+		case "IDAT":
+			// 没有fcTL的默认图像不属于动画
+			if current != nil {
+				current.data.Write(c.data)
+			}
+		case "fdAT":
+			if current == nil || len(c.data) < 4 {
+				return nil, errors.New("apng: fdAT without fcTL")
+			}
+			current.data.Write(c.data[4:])
+		case "acTL", "IEND":
+		default:
+			if current == nil {
+				shared = append(shared, c)
+			}
+		}
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("apng: no frames")
+	}
+	if err := checkCanvas(width, height, len(frames)); err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, width, height))
+	result := make([]Frame, len(frames))
+	for i, f := range frames {
+		img, err := decodeAPNGFrame(ihdr, shared, f)
+		if err != nil {
+			return nil, fmt.Errorf("apng: frame %d: %w", i, err)
+		}
+		rect := image.Rect(f.x, f.y, f.x+f.width, f.y+f.height)
+		if !rect.In(canvas.Bounds()) {
+			return nil, fmt.Errorf("apng: frame %d is outside the canvas", i)
+		}
+
+		var previous *image.NRGBA
+		if f.dispose == apngDisposePrevious {
+			previous = cloneNRGBA(canvas)
+		}
+
+		op := draw.Src
+		if f.blend == apngBlendOver {
+			op = draw.Over
+		}
+		draw.Draw(canvas, rect, img, img.Bounds().Min, op)
+
+		delay := f.delay
+		if delay <= 0 {
+			delay = defaultFrameDelay
+		}
+		result[i] = Frame{Image: cloneNRGBA(canvas), Delay: delay}
+
+		switch f.dispose {
+		case apngDisposeBackground:
+			draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			canvas = previous
+		}
+	}
+	return result, nil
+}
+
+// 将一帧的数据组装为独立的PNG并解码
+func decodeAPNGFrame(ihdr []byte, shared []pngChunk, f *apngFrame) (image.Image, error) {
+	header := bytes.Clone(ihdr)
+	binary.BigEndian.PutUint32(header[0:], uint32(f.width))
+	binary.BigEndian.PutUint32(header[4:], uint32(f.height))
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writePNGChunk(&buf, "IHDR", header)
+	for _, c := range shared {
+		writePNGChunk(&buf, c.typ, c.data)
+	}
+	writePNGChunk(&buf, "IDAT", f.data.Bytes())
+	writePNGChunk(&buf, "IEND", nil)
+	return png.Decode(&buf)
+}
+
+// 写入一个PNG数据块
+func writePNGChunk(buf *bytes.Buffer, typ string, data []byte) {
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write([]byte(typ))
+	_, _ = crc.Write(data)
+	buf.WriteString(typ)
+	buf.Write(data)
+	_ = binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// 复制图像
+func cloneNRGBA(img *image.NRGBA) *image.NRGBA {
+	c := image.NewNRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+	return c
+}
+
+// DarkMode 从浅色帧生成深色帧的方式
+type DarkMode string
+
+const (
+	// DarkInvert 反转颜色，保留透明度
+	DarkInvert DarkMode = "invert"
+	// DarkRecolor 将所有可见像素染成白色，保留透明度
+	DarkRecolor DarkMode = "recolor"
+	// DarkCopy 使用与浅色相同的帧
+	DarkCopy DarkMode = "copy"
+)
+
+// ParseDarkMode 解析深色帧的生成方式
+func ParseDarkMode(s string) (DarkMode, error) {
+	switch mode := DarkMode(strings.ToLower(s)); mode {
+	case DarkInvert, DarkRecolor, DarkCopy:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown dark mode %q (allowed: invert, recolor, copy)", s)
+	}
+}
+
+// DarkVariant 按指定方式从浅色帧生成深色帧
+func DarkVariant(img image.Image, mode DarkMode) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+	if mode == DarkCopy {
+		return dst
+	}
+
+	for i := 0; i < len(dst.Pix); i += 4 {
+		switch mode {
+		case DarkInvert:
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = 255-dst.Pix[i], 255-dst.Pix[i+1], 255-dst.Pix[i+2]
+		case DarkRecolor:
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = 255, 255, 255
+		}
+	}
+	return dst
+}
+
+// ImportOptions 导入角色的选项
+type ImportOptions struct {
+	// 菜单中显示的名称，为空时使用目录名
+	Name string
+	// 作者
+	Author string
+	// PNG精灵图的排列方式
+	Layout SpriteLayout
+	// 生成的PNG帧的边长，为0时使用DefaultFrameSize，不能超过MaxFrameSize
+	Size int
+	// 深色帧的生成方式，为空时使用DarkInvert
+	Dark DarkMode
+	// 目录已存在时是否覆盖
+	Force bool
+}
+
+// ImportRunner 将动画或精灵图转换为角色目录：每帧生成Windows使用的ICO和其他平台使用的PNG，
+// 深色帧由浅色帧生成，帧延迟写入runner.yaml
+func ImportRunner(data []byte, dir string, opts ImportOptions) (*Manifest, error) {
+	if opts.Size < 0 || opts.Size > MaxFrameSize {
+		return nil, fmt.Errorf("convert: frame size %d must be between 1 and %d", opts.Size, MaxFrameSize)
+	}
+	frames, err := DecodeAnimation(data, opts.Layout)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("convert: no frames")
+	}
+	if opts.Size == 0 {
+		opts.Size = DefaultFrameSize
+	}
+	if opts.Dark == "" {
+		opts.Dark = DarkInvert
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 && !opts.Force {
+		return nil, fmt.Errorf("convert: %s already exists", dir)
+	}
+
+	manifest := &Manifest{
+		Name:   opts.Name,
+		Author: opts.Author,
+		Frames: make([]ManifestFrame, len(frames)),
+	}
+	if manifest.Name == "" {
+		manifest.Name = filepath.Base(dir)
+	}
+	durations := frameDurations(frames)
+
+	for _, t := range supportThemes {
+		themeDir := filepath.Join(dir, string(t))
+		if err := os.MkdirAll(themeDir, 0755); err != nil {
+			return nil, err
+		}
+		for i, frame := range frames {
+			img := frame.Image
+			if t == theme.DarkType {
+				img = DarkVariant(img, opts.Dark)
+			}
+			stem := fmt.Sprintf("frame_%d", i)
+			if err := writeFrame(filepath.Join(themeDir, stem), img, opts.Size); err != nil {
+				return nil, err
+			}
+			manifest.Frames[i] = ManifestFrame{File: stem, Duration: durations[i]}
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), manifest.Marshal(), 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// 写入一帧的ICO和PNG文件
+func writeFrame(stem string, img image.Image, size int) error {
+	icons := make([]image.Image, len(icoSizes))
+	for i, s := range icoSizes {
+		icons[i] = ResizeFrame(img, s)
+	}
+	ico, err := EncodeICO(icons...)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(stem+".ico", ico, 0644); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, ResizeFrame(img, size)); err != nil {
+		return err
+	}
+	return os.WriteFile(stem+".png", buf.Bytes(), 0644)
+}
+
+// 将帧延迟转换为相对时长，所有帧延迟相同时返回0（即默认值1）
+func frameDurations(frames []Frame) []float64 {
+	durations := make([]float64, len(frames))
+	shortest := time.Duration(math.MaxInt64)
+	uniform := true
+	for _, f := range frames {
+		if f.Delay > 0 {
+			shortest = min(shortest, f.Delay)
+		}
+		uniform = uniform && f.Delay == frames[0].Delay
+	}
+	if uniform {
+		return durations
+	}
+	for i, f := range frames {
+		if f.Delay > 0 {
+			durations[i] = math.Round(float64(f.Delay)/float64(shortest)*100) / 100
+		}
+	}
+	return durations
+}
+
+// Marshal 将清单编码为YAML
+func (m *Manifest) Marshal() []byte {
+	var buf bytes.Buffer
+	if m.Name != "" {
+		fmt.Fprintf(&buf, "name: %s\n", strconv.Quote(m.Name))
+	}
+	if m.Author != "" {
+		fmt.Fprintf(&buf, "author: %s\n", strconv.Quote(m.Author))
+	}
+	if len(m.Frames) > 0 {
+		buf.WriteString("frames:\n")
+		for _, f := range m.Frames {
+			fmt.Fprintf(&buf, "  - file: %s\n", strconv.Quote(f.File))
+			if f.Duration != 0 && f.Duration != 1 {
+				fmt.Fprintf(&buf, "    duration: %s\n", strconv.FormatFloat(f.Duration, 'f', -1, 64))
+			}
+		}
+	}
+	if len(m.Themes) > 0 {
+		buf.WriteString("themes:\n")
+		for _, t := range supportThemes {
+			if dir, ok := m.Themes[string(t)]; ok {
+				fmt.Fprintf(&buf, "  %s: %s\n", t, strconv.Quote(dir))
+			}
+		}
+	}
+	if m.MinSpeed != 0 {
+		fmt.Fprintf(&buf, "min_speed: %s\n", strconv.FormatFloat(m.MinSpeed, 'f', -1, 64))
+	}
+	if m.MaxSpeed != 0 {
+		fmt.Fprintf(&buf, "max_speed: %s\n", strconv.FormatFloat(m.MaxSpeed, 'f', -1, 64))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,479 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+)
+
+var (
+	red   = color.NRGBA{R: 0xff, A: 0xff}
+	blue  = color.NRGBA{B: 0xff, A: 0xff}
+	black = color.NRGBA{A: 0xff}
+)
+
+// 填充纯色图像
+func solid(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// 横向拼接纯色帧为精灵图
+func sheet(colors ...color.Color) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 4*len(colors), 4))
+	for i, c := range colors {
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				img.Set(i*4+x, y, c)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func TestSliceSprite(t *testing.T) {
+	grid := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for i, c := range []color.Color{red, blue, black} {
+		grid.Set(i%2*4, i/2*4, c)
+	}
+
+	tests := []struct {
+		name   string
+		sheet  image.Image
+		layout SpriteLayout
+		want   int
+		err    bool
+	}{
+		{"horizontal inferred", solid(12, 4, red), SpriteLayout{}, 3, false},
+		{"vertical inferred", solid(4, 16, red), SpriteLayout{}, 4, false},
+		{"columns only", solid(12, 6, red), SpriteLayout{Columns: 3}, 3, false},
+		{"grid with partial row", grid, SpriteLayout{Columns: 2, Rows: 2, Frames: 3}, 3, false},
+		{"not divisible", solid(10, 4, red), SpriteLayout{Columns: 3}, 0, true},
+		{"cannot infer", solid(10, 4, red), SpriteLayout{}, 0, true},
+		{"too many frames", grid, SpriteLayout{Columns: 2, Rows: 2, Frames: 5}, 0, true},
+	}
+	for _, tt := range tests {
+		frames, err := SliceSprite(tt.sheet, tt.layout)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(frames) != tt.want {
+			t.Fatalf("%s: got %d frames, want %d", tt.name, len(frames), tt.want)
+		}
+	}
+
+	// 网格按行优先顺序切分
+	frames, _ := SliceSprite(grid, SpriteLayout{Columns: 2, Rows: 2, Frames: 3})
+	for i, want := range []color.Color{red, blue, black} {
+		if got := frames[i].At(0, 0); !sameColor(got, want) {
+			t.Errorf("grid frame %d is %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestDecodeGIF(t *testing.T) {
+	// 第二帧只覆盖左半部分，右半部分保留第一帧的内容
+	p := color.Palette{color.Transparent, red, blue}
+	first := image.NewPaletted(image.Rect(0, 0, 4, 4), p)
+	for i := range first.Pix {
+		first.Pix[i] = 1
+	}
+	second := image.NewPaletted(image.Rect(0, 0, 2, 4), p)
+	for i := range second.Pix {
+		second.Pix[i] = 2
+	}
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:    []*image.Paletted{first, second},
+		Delay:    []int{5, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := DecodeAnimation(buf.Bytes(), SpriteLayout{})
+	if err != nil {
+		t.Fatalf("DecodeAnimation: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if frames[0].Delay != 50*time.Millisecond || frames[1].Delay != 100*time.Millisecond {
+		t.Fatalf("delays %v %v, want 50ms 100ms", frames[0].Delay, frames[1].Delay)
+	}
+	if got := frames[1].Image.At(0, 0); !sameColor(got, blue) {
+		t.Errorf("second frame left half is %v, want blue", got)
+	}
+	if got := frames[1].Image.At(3, 0); !sameColor(got, red) {
+		t.Errorf("second frame right half is %v, want red", got)
+	}
+}
+
+// 组装一个两帧的APNG，第二帧只覆盖左上角
+func buildAPNG(t *testing.T) []byte {
+	t.Helper()
+
+	encode := func(img image.Image) (ihdr, idat []byte) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := readPNGChunks(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range chunks {
+			switch c.typ {
+			case "IHDR":
+				ihdr = c.data
+			case "IDAT":
+				idat = append(idat, c.data...)
+			}
+		}
+		return ihdr, idat
+	}
+	fcTL := func(seq uint32, w, h, x, y uint32, delayNum uint16) []byte {
+		b := make([]byte, 26)
+		binary.BigEndian.PutUint32(b[0:], seq)
+		binary.BigEndian.PutUint32(b[4:], w)
+		binary.BigEndian.PutUint32(b[8:], h)
+		binary.BigEndian.PutUint32(b[12:], x)
+		binary.BigEndian.PutUint32(b[16:], y)
+		binary.BigEndian.PutUint16(b[20:], delayNum)
+		binary.BigEndian.PutUint16(b[22:], 100)
+		b[25] = apngBlendOver
+		return b
+	}
+
+	ihdr, idat1 := encode(solid(4, 4, red))
+	_, idat2 := encode(solid(2, 2, blue))
+
+	acTL := make([]byte, 8)
+	binary.BigEndian.PutUint32(acTL, 2)
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writePNGChunk(&buf, "IHDR", ihdr)
+	writePNGChunk(&buf, "acTL", acTL)
+	writePNGChunk(&buf, "fcTL", fcTL(0, 4, 4, 0, 0, 10))
+	writePNGChunk(&buf, "IDAT", idat1)
+	writePNGChunk(&buf, "fcTL", fcTL(1, 2, 2, 0, 0, 30))
+	writePNGChunk(&buf, "fdAT", append([]byte{0, 0, 0, 2}, idat2...))
+	writePNGChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func TestDecodeAPNG(t *testing.T) {
+	data := buildAPNG(t)
+	if !isAPNG(data) || isAPNG(sheet(red)) {
+		t.Fatal("isAPNG misdetects the format")
+	}
+
+	frames, err := DecodeAnimation(data, SpriteLayout{})
+	if err != nil {
+		t.Fatalf("DecodeAnimation: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if frames[0].Delay != 100*time.Millisecond || frames[1].Delay != 300*time.Millisecond {
+		t.Fatalf("delays %v %v, want 100ms 300ms", frames[0].Delay, frames[1].Delay)
+	}
+	if got := frames[1].Image.At(0, 0); !sameColor(got, blue) {
+		t.Errorf("second frame corner is %v, want blue", got)
+	}
+	if got := frames[1].Image.At(3, 3); !sameColor(got, red) {
+		t.Errorf("second frame keeps %v outside its region, want red", got)
+	}
+}
+
+func TestCountGIFFrames(t *testing.T) {
+	p := color.Palette{color.Transparent, red, blue}
+	frame := func(w, h int) *image.Paletted {
+		return image.NewPaletted(image.Rect(0, 0, w, h), p)
+	}
+	// 带有局部颜色表的帧
+	local := image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{black, red, blue, color.Transparent, red})
+
+	tests := []struct {
+		name string
+		gif  *gif.GIF
+	}{
+		{"single", &gif.GIF{Image: []*image.Paletted{frame(4, 4)}, Delay: []int{0}}},
+		{"disposal and delays", &gif.GIF{
+			Image:    []*image.Paletted{frame(4, 4), frame(2, 4), frame(4, 2)},
+			Delay:    []int{5, 10, 20},
+			Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious},
+		}},
+		{"local palette", &gif.GIF{Image: []*image.Paletted{frame(2, 2), local}, Delay: []int{1, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := gif.EncodeAll(&buf, tt.gif); err != nil {
+				t.Fatal(err)
+			}
+			got, err := countGIFFrames(buf.Bytes())
+			if err != nil {
+				t.Fatalf("countGIFFrames: %v", err)
+			}
+			if got != len(tt.gif.Image) {
+				t.Errorf("got %d frames, want %d", got, len(tt.gif.Image))
+			}
+
+			// 截断的数据
+			if _, err := countGIFFrames(buf.Bytes()[:buf.Len()/2]); err == nil {
+				t.Error("countGIFFrames accepted truncated data")
+			}
+		})
+	}
+}
+
+func TestDecodeGIFTooLarge(t *testing.T) {
+	// 每帧都很小，但合成时每帧都需要完整的画布
+	p := color.Palette{color.Transparent, red}
+	images := make([]*image.Paletted, 5)
+	for i := range images {
+		images[i] = image.NewPaletted(image.Rect(0, 0, 1, 1), p)
+	}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:  images,
+		Delay:  make([]int, len(images)),
+		Config: image.Config{ColorModel: p, Width: 4096, Height: 4096},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeAnimation(buf.Bytes(), SpriteLayout{}); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("got %v, want a too large error", err)
+	}
+}
+
+func TestDecodeAPNGMalformed(t *testing.T) {
+	// 修改buildAPNG生成的数据块后重新组装
+	rewrite := func(modify func(typ string, index int, data []byte)) []byte {
+		chunks, err := readPNGChunks(buildAPNG(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		buf.Write(pngSignature)
+		counts := make(map[string]int)
+		for _, c := range chunks {
+			modify(c.typ, counts[c.typ], c.data)
+			counts[c.typ]++
+			writePNGChunk(&buf, c.typ, c.data)
+		}
+		return buf.Bytes()
+	}
+
+	tests := map[string]func(typ string, index int, data []byte){
+		"huge canvas": func(typ string, _ int, data []byte) {
+			if typ == "IHDR" {
+				binary.BigEndian.PutUint32(data[0:], 1<<30)
+				binary.BigEndian.PutUint32(data[4:], 1<<30)
+			}
+		},
+		"frame outside canvas": func(typ string, index int, data []byte) {
+			if typ == "fcTL" && index == 1 {
+				binary.BigEndian.PutUint32(data[12:], 3)
+			}
+		},
+		"overflowing frame offset": func(typ string, index int, data []byte) {
+			if typ == "fcTL" && index == 1 {
+				binary.BigEndian.PutUint32(data[4:], 0xffffffff)
+				binary.BigEndian.PutUint32(data[12:], 0xffffffff)
+			}
+		},
+		"empty frame": func(typ string, index int, data []byte) {
+			if typ == "fcTL" && index == 1 {
+				binary.BigEndian.PutUint32(data[4:], 0)
+			}
+		},
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeAnimation(rewrite(modify), SpriteLayout{}); err == nil {
+				t.Fatal("DecodeAnimation accepted a malformed APNG")
+			}
+		})
+	}
+
+	// 普通PNG精灵图同样在解码前检查尺寸
+	if _, err := DecodeAnimation(resizedPNGHeader(t, 1<<20, 1<<20), SpriteLayout{}); err == nil {
+		t.Error("DecodeAnimation accepted an oversized sprite sheet")
+	}
+}
+
+func TestDecodeAnimationUnsupported(t *testing.T) {
+	if _, err := DecodeAnimation([]byte("not an image"), SpriteLayout{}); err == nil {
+		t.Fatal("expected an error for unsupported data")
+	}
+}
+
+func TestResizeFrame(t *testing.T) {
+	// 2:1的图像缩放后居中，上下留白
+	img := ResizeFrame(solid(64, 32, red), 16)
+	if img.Bounds().Dx() != 16 || img.Bounds().Dy() != 16 {
+		t.Fatalf("size %v, want 16x16", img.Bounds())
+	}
+	if got := img.At(8, 8); !sameColor(got, red) {
+		t.Errorf("center is %v, want red", got)
+	}
+	if _, _, _, a := img.At(8, 0).RGBA(); a != 0 {
+		t.Errorf("top row alpha %d, want transparent", a)
+	}
+}
+
+func TestDarkVariant(t *testing.T) {
+	src := solid(1, 1, color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x80})
+	tests := []struct {
+		mode DarkMode
+		want color.NRGBA
+	}{
+		{DarkInvert, color.NRGBA{R: 0xef, G: 0xdf, B: 0xcf, A: 0x80}},
+		{DarkRecolor, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}},
+		{DarkCopy, color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x80}},
+	}
+	for _, tt := range tests {
+		if got := DarkVariant(src, tt.mode).NRGBAAt(0, 0); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+
+	if _, err := ParseDarkMode("sepia"); err == nil {
+		t.Error("ParseDarkMode accepted an unknown mode")
+	}
+}
+
+// 将PNG文件头中的尺寸改为width x height，像素数据不变
+func resizedPNGHeader(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solid(1, 1, red)); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := readPNGChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	for _, c := range chunks {
+		if c.typ == "IHDR" {
+			binary.BigEndian.PutUint32(c.data[0:], width)
+			binary.BigEndian.PutUint32(c.data[4:], height)
+		}
+		writePNGChunk(&out, c.typ, c.data)
+	}
+	return out.Bytes()
+}
+
+func TestImportRunner(t *testing.T) {
+	// 使用调色板GIF，延迟不同的帧写入相对时长
+	frame := func(c color.Color) *image.Paletted {
+		img := image.NewPaletted(image.Rect(0, 0, 8, 8), palette.Plan9)
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image: []*image.Paletted{frame(black), frame(black), frame(black)},
+		Delay: []int{10, 10, 20},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	dir := root + "/mascot"
+	manifest, err := ImportRunner(buf.Bytes(), dir, ImportOptions{Author: "Our Team"})
+	if err != nil {
+		t.Fatalf("ImportRunner: %v", err)
+	}
+	if manifest.Name != "mascot" || len(manifest.Frames) != 3 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	// 目录已存在时需要Force
+	if _, err := ImportRunner(buf.Bytes(), dir, ImportOptions{}); err == nil {
+		t.Error("ImportRunner overwrote an existing runner without Force")
+	}
+
+	// 生成的帧不能超过角色包和资源校验允许的尺寸
+	for _, size := range []int{-1, MaxFrameSize + 1} {
+		if _, err := ImportRunner(buf.Bytes(), root+"/sized", ImportOptions{Size: size}); err == nil {
+			t.Errorf("ImportRunner accepted frame size %d", size)
+		}
+	}
+
+	m := NewResourceManager(os.DirFS(t.TempDir()))
+	if err := m.LoadRunners(os.DirFS(root)); err != nil {
+		t.Fatalf("LoadRunners: %v", err)
+	}
+	info := m.RunnerInfo("mascot")
+	if info.Author != "Our Team" || !slices.Equal(info.Timing.Durations, []float64{1, 1, 2}) {
+		t.Fatalf("unexpected runner info %+v", info)
+	}
+
+	for _, th := range supportThemes {
+		icons, err := m.LoadIcons("mascot", th)
+		if err != nil {
+			t.Fatalf("LoadIcons(%s): %v", th, err)
+		}
+		if len(icons) != 3 {
+			t.Fatalf("%s: got %d frames, want 3", th, len(icons))
+		}
+		img, err := DecodeIcon(icons[0])
+		if err != nil {
+			t.Fatalf("%s: DecodeIcon: %v", th, err)
+		}
+
+		// 深色帧由浅色帧反色生成
+		want := color.Color(black)
+		if th == theme.DarkType {
+			want = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		}
+		b := img.Bounds()
+		if got := img.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2); !sameColor(got, want) {
+			t.Errorf("%s: frame color %v, want %v", th, got, want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// ICO文件头和目录项的大小
+const (
+	icoHeaderSize = 6
+	icoEntrySize  = 16
+)
+
+// 图标的最大边长，ICO格式无法表示更大的图像
+const maxIconSize = 256
+
+// icoEntry ICO目录项
+type icoEntry struct {
+	width, height int
+	size, offset  uint32
+}
+
+// 读取ICO目录
+func readICOEntries(data []byte) ([]icoEntry, error) {
+	if len(data) < icoHeaderSize {
+		return nil, errors.New("ico: file too short")
+	}
+	if binary.LittleEndian.Uint16(data[0:]) != 0 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil, errors.New("ico: invalid header")
+	}
+
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 {
+		return nil, errors.New("ico: no images")
+	}
+	if len(data) < icoHeaderSize+count*icoEntrySize {
+		return nil, errors.New("ico: truncated directory")
+	}
+
+	entries := make([]icoEntry, count)
+	for i := range entries {
+		e := data[icoHeaderSize+i*icoEntrySize:]
+		entry := icoEntry{
+			width:  int(e[0]),
+			height: int(e[1]),
+			size:   binary.LittleEndian.Uint32(e[8:]),
+			offset: binary.LittleEndian.Uint32(e[12:]),
+		}
+		// 0 表示 256 像素
+		if entry.width == 0 {
+			entry.width = 256
+		}
+		if entry.height == 0 {
+			entry.height = 256
+		}
+		if uint64(entry.offset)+uint64(entry.size) > uint64(len(data)) {
+			return nil, fmt.Errorf("ico: image %d out of bounds", i)
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// DecodeICO 解码ICO文件中尺寸最大的图像，支持PNG和32位BMP格式
+func DecodeICO(data []byte) (image.Image, error) {
+	entries, err := readICOEntries(data)
+	if err != nil {
+		return nil, err
+	}
+
+	largest := entries[0]
+	for _, e := range entries[1:] {
+		if e.width*e.height > largest.width*largest.height {
+			largest = e
+		}
+	}
+
+	payload := data[largest.offset : largest.offset+largest.size]
+	if bytes.HasPrefix(payload, pngSignature) {
+		return decodeIconPNG(payload)
+	}
+	return decodeICOBitmap(payload)
+}
+
+// DecodeIcon 解码一帧图标，支持PNG文件和ICO文件
+func DecodeIcon(data []byte) (image.Image, error) {
+	if bytes.HasPrefix(data, pngSignature) {
+		return decodeIconPNG(data)
+	}
+	return DecodeICO(data)
+}
+
+// 解码PNG格式的图标，解码前检查尺寸，避免按文件头声明的尺寸分配内存
+func decodeIconPNG(data []byte) (image.Image, error) {
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxIconSize || config.Height > maxIconSize {
+		return nil, fmt.Errorf("icon size %dx%d exceeds %dx%d", config.Width, config.Height, maxIconSize, maxIconSize)
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+// EncodeICO 将图像编码为ICO文件，每个图像作为一个PNG格式的条目
+func EncodeICO(images ...image.Image) ([]byte, error) {
+	if len(images) == 0 {
+		return nil, errors.New("ico: no images")
+	}
+
+	payloads := make([][]byte, len(images))
+	for i, img := range images {
+		if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w > 256 || h > 256 || w == 0 || h == 0 {
+			return nil, fmt.Errorf("ico: image %d has unsupported size %dx%d", i, w, h)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		payloads[i] = buf.Bytes()
+	}
+
+	header := make([]byte, icoHeaderSize+len(images)*icoEntrySize)
+	binary.LittleEndian.PutUint16(header[2:], 1)
+	binary.LittleEndian.PutUint16(header[4:], uint16(len(images)))
+
+	offset := len(header)
+	for i, img := range images {
+		e := header[icoHeaderSize+i*icoEntrySize:]
+		// 256 像素记为 0
+		e[0] = byte(img.Bounds().Dx())
+		e[1] = byte(img.Bounds().Dy())
+		binary.LittleEndian.PutUint16(e[4:], 1)
+		binary.LittleEndian.PutUint16(e[6:], 32)
+		binary.LittleEndian.PutUint32(e[8:], uint32(len(payloads[i])))
+		binary.LittleEndian.PutUint32(e[12:], uint32(offset))
+		offset += len(payloads[i])
+	}
+
+	return bytes.Join(append([][]byte{header}, payloads...), nil), nil
+}
+
+// PNG文件签名
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// 解码ICO中的32位BMP图像（不含文件头，高度包含AND掩码）
+func decodeICOBitmap(data []byte) (image.Image, error) {
+	const headerSize = 40
+	if len(data) < headerSize {
+		return nil, errors.New("ico: invalid bitmap header")
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if size < headerSize || uint64(size) > uint64(len(data)) {
+		return nil, errors.New("ico: invalid bitmap header")
+	}
+
+	width := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+	bpp := binary.LittleEndian.Uint16(data[14:])
+	if width <= 0 || height <= 0 || width > maxIconSize || height > maxIconSize {
+		return nil, fmt.Errorf("ico: invalid bitmap size %dx%d", width, height)
+	}
+	if bpp != 32 {
+		return nil, fmt.Errorf("ico: unsupported bitmap depth %d", bpp)
+	}
+
+	pixels := data[size:]
+	stride := width * 4
+	if uint64(len(pixels)) < uint64(stride)*uint64(height) {
+		return nil, errors.New("ico: truncated bitmap")
+	}
+
+	// AND掩码每行按4字节对齐，1表示透明
+	maskStride := (width + 31) / 32 * 4
+	mask := pixels[stride*height:]
+	hasMask := len(mask) >= maskStride*height
+
+	// 部分旧图标的alpha通道全为0，此时使用AND掩码
+	hasAlpha := false
+	for i := 3; i < stride*height; i += 4 {
+		if pixels[i] != 0 {
+			hasAlpha = true
+			break
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		// BMP按从下到上的顺序存储
+		row := pixels[(height-1-y)*stride:]
+		maskRow := mask[min(len(mask), (height-1-y)*maskStride):]
+		for x := 0; x < width; x++ {
+			b, g, r, a := row[x*4], row[x*4+1], row[x*4+2], row[x*4+3]
+			if !hasAlpha {
+				a = 0xff
+				if hasMask && maskRow[x/8]&(0x80>>(x%8)) != 0 {
+					a = 0
+				}
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: r, G: g, B: b, A: a})
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,81 @@
+package resource
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestEncodeICO(t *testing.T) {
+	data, err := EncodeICO(solid(16, 16, red), solid(32, 32, blue))
+	if err != nil {
+		t.Fatalf("EncodeICO: %v", err)
+	}
+	entries, err := readICOEntries(data)
+	if err != nil {
+		t.Fatalf("readICOEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].width != 16 || entries[1].width != 32 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	// 解码时选择最大的图像
+	img, err := DecodeICO(data)
+	if err != nil {
+		t.Fatalf("DecodeICO: %v", err)
+	}
+	if img.Bounds().Dx() != 32 || !sameColor(img.At(0, 0), blue) {
+		t.Fatalf("decoded %v with color %v, want 32px blue", img.Bounds(), img.At(0, 0))
+	}
+
+	if _, err := EncodeICO(solid(300, 300, red)); err == nil {
+		t.Error("EncodeICO accepted an image larger than 256px")
+	}
+}
+
+// 只有一个条目的ICO文件
+func singleICO(payload []byte) []byte {
+	header := make([]byte, icoHeaderSize+icoEntrySize)
+	binary.LittleEndian.PutUint16(header[2:], 1)
+	binary.LittleEndian.PutUint16(header[4:], 1)
+	binary.LittleEndian.PutUint32(header[icoHeaderSize+8:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[icoHeaderSize+12:], uint32(len(header)))
+	return append(header, payload...)
+}
+
+// ICO中的BMP图像，pixels为头部之后的数据长度
+func icoBitmap(headerSize uint32, width, height int32, pixels int) []byte {
+	data := make([]byte, 40+pixels)
+	binary.LittleEndian.PutUint32(data[0:], headerSize)
+	binary.LittleEndian.PutUint32(data[4:], uint32(width))
+	binary.LittleEndian.PutUint32(data[8:], uint32(height*2))
+	binary.LittleEndian.PutUint16(data[14:], 32)
+	return data
+}
+
+func TestDecodeICOMalformed(t *testing.T) {
+	if img, err := DecodeICO(singleICO(icoBitmap(40, 2, 2, 2*2*4))); err != nil || img.Bounds().Dx() != 2 {
+		t.Fatalf("valid bitmap: %v, %v", img, err)
+	}
+
+	tests := map[string][]byte{
+		"header larger than data": singleICO(icoBitmap(0x7fffffff, 2, 2, 16)),
+		"header too small":        singleICO(icoBitmap(12, 2, 2, 16)),
+		"bitmap too wide":         singleICO(icoBitmap(40, 1<<20, 1, 16)),
+		"bitmap too tall":         singleICO(icoBitmap(40, 1, 1<<29, 16)),
+		"overflowing size":        singleICO(icoBitmap(40, 0x7fffffff, 0x3fffffff, 16)),
+		"truncated pixels":        singleICO(icoBitmap(40, 16, 16, 16)),
+		"oversized png":           singleICO(resizedPNGHeader(t, 1<<20, 1<<20)),
+		"truncated directory":     singleICO(nil)[:icoHeaderSize+4],
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeICO(data); err == nil {
+				t.Fatal("DecodeICO accepted malformed data")
+			}
+		})
+	}
+
+	if _, err := DecodeIcon(resizedPNGHeader(t, 1<<20, 1<<20)); err == nil {
+		t.Error("DecodeIcon accepted an oversized PNG")
+	}
+}
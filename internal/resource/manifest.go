@@ -0,0 +1,277 @@
+package resource
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/eatmoreapple/go-runcat/internal/animation"
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+	"github.com/spf13/viper"
+)
+
+// ManifestFile 角色清单的文件名，位于角色目录下
+const ManifestFile = "runner.yaml"
+
+// Manifest 角色清单，描述角色的元数据、帧列表和动画节奏
+//
+//	name: Mascot
+//	author: Our Team
+//	frames:
+//	  - file: run_0.png
+//	  - file: run_1.png
+//	    duration: 2
+//	themes:
+//	  light: light
+//	  dark: dark
+//	min_speed: 1
+//	max_speed: 10
+//
+// 也可以用一张精灵图代替逐帧文件：
+//
+//	sprite:
+//	  file: sheet.png
+//	  columns: 4
+//	  rows: 2
+type Manifest struct {
+	// 菜单中显示的名称，为空时使用目录名
+	Name string `mapstructure:"name"`
+	// 作者
+	Author string `mapstructure:"author"`
+	// 帧列表，文件相对于主题目录；为空时按文件名末尾的数字排序
+	Frames []ManifestFrame `mapstructure:"frames"`
+	// 精灵图，代替逐帧文件
+	Sprite *ManifestSprite `mapstructure:"sprite"`
+	// 主题到目录的映射，为空时使用light和dark子目录；缺少的主题使用另一个主题的帧
+	Themes map[string]string `mapstructure:"themes"`
+	// 最低速度倍数，为0时使用默认值
+	MinSpeed float64 `mapstructure:"min_speed"`
+	// 最高速度倍数，为0时使用默认值
+	MaxSpeed float64 `mapstructure:"max_speed"`
+}
+
+// ManifestFrame 清单中的一帧
+type ManifestFrame struct {
+	// 帧文件名，省略扩展名时按平台选择ICO或PNG
+	File string `mapstructure:"file"`
+	// 相对时长，为0时为1
+	Duration float64 `mapstructure:"duration"`
+}
+
+// ManifestSprite 清单中的精灵图，每个主题目录中各有一张
+type ManifestSprite struct {
+	// 精灵图文件名，必须是PNG
+	File string `mapstructure:"file"`
+	// 列数，为0时根据图像尺寸推断
+	Columns int `mapstructure:"columns"`
+	// 行数，为0时根据图像尺寸推断
+	Rows int `mapstructure:"rows"`
+	// 帧数，最后一行未填满时使用
+	Frames int `mapstructure:"frames"`
+}
+
+// ParseManifest 解析并校验角色清单
+func ParseManifest(data []byte) (*Manifest, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+
+	var m Manifest
+	if err := v.UnmarshalExact(&m); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate 校验清单，返回所有发现的问题
+func (m *Manifest) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{ManifestFile}, args...)...))
+	}
+
+	for i, frame := range m.Frames {
+		switch {
+		case frame.File == "":
+			invalid("frames[%d]: file is required", i)
+		case !fs.ValidPath(frame.File) || strings.Contains(frame.File, "/"):
+			invalid("frames[%d]: file %q must be a plain file name", i, frame.File)
+		case path.Ext(frame.File) != "" && !slices.Contains(frameExtensions, strings.ToLower(path.Ext(frame.File))):
+			invalid("frames[%d]: file %q must be one of %s", i, frame.File, strings.Join(frameExtensions, ", "))
+		}
+		if frame.Duration < 0 {
+			invalid("frames[%d]: duration %v must not be negative", i, frame.Duration)
+		}
+	}
+
+	if s := m.Sprite; s != nil {
+		switch {
+		case s.File == "":
+			invalid("sprite: file is required")
+		case !fs.ValidPath(s.File) || strings.Contains(s.File, "/"):
+			invalid("sprite: file %q must be a plain file name", s.File)
+		case strings.ToLower(path.Ext(s.File)) != ".png":
+			invalid("sprite: file %q must be a .png image", s.File)
+		}
+		if s.Columns < 0 || s.Rows < 0 || s.Frames < 0 {
+			invalid("sprite: columns, rows and frames must not be negative")
+		}
+		if len(m.Frames) > 0 {
+			invalid("sprite and frames cannot be used together")
+		}
+	}
+
+	for name, dir := range m.Themes {
+		if !slices.Contains(supportThemes, theme.Type(name)) {
+			invalid("themes: unknown theme %q (allowed: light, dark)", name)
+		}
+		if !fs.ValidPath(dir) {
+			invalid("themes.%s: directory %q must be relative to the runner directory", name, dir)
+		}
+	}
+
+	// 取反比较同时排除NaN
+	if !(m.MinSpeed >= 0 && m.MinSpeed <= animation.MaxSpeed) {
+		invalid("min_speed %v must be between 0 and %v", m.MinSpeed, animation.MaxSpeed)
+	}
+	if !(m.MaxSpeed >= 0 && m.MaxSpeed <= animation.MaxSpeed) {
+		invalid("max_speed %v must be between 0 and %v", m.MaxSpeed, animation.MaxSpeed)
+	}
+	// 与实际生效的最高速度比较，max_speed为0时使用默认值
+	if maxSpeed := cmp.Or(m.MaxSpeed, animation.MaxSpeed); m.MinSpeed > maxSpeed {
+		invalid("min_speed %v is greater than max_speed %v", m.MinSpeed, maxSpeed)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Timing 返回清单描述的动画节奏
+func (m *Manifest) Timing() animation.Timing {
+	timing := animation.Timing{
+		MinSpeed: m.MinSpeed,
+		MaxSpeed: m.MaxSpeed,
+	}
+	if len(m.Frames) > 0 {
+		timing.Durations = make([]float64, len(m.Frames))
+		for i, frame := range m.Frames {
+			timing.Durations[i] = cmp.Or(frame.Duration, 1)
+		}
+	}
+	return timing
+}
+
+// 各主题的目录，清单未指定时使用light和dark子目录
+func (m *Manifest) themeDirs() map[theme.Type]string {
+	dirs := make(map[theme.Type]string)
+	if len(m.Themes) == 0 {
+		for _, t := range supportThemes {
+			dirs[t] = string(t)
+		}
+		return dirs
+	}
+	for name, dir := range m.Themes {
+		dirs[theme.Type(name)] = dir
+	}
+	return dirs
+}
+
+// 按清单扫描各主题的帧文件，没有清单时按命名约定扫描
+func (m *Manifest) scanFrames(fsys fs.FS, dir string) (map[theme.Type]themeFrames, error) {
+	frames := make(map[theme.Type]themeFrames)
+	for t, sub := range m.themeDirs() {
+		themeDir := path.Join(dir, sub)
+		entries, err := fs.ReadDir(fsys, themeDir)
+		if err != nil {
+			// 未在清单中声明的主题可以省略
+			if errors.Is(err, fs.ErrNotExist) && len(m.Themes) == 0 {
+				continue
+			}
+			return nil, err
+		}
+
+		if m.Sprite != nil {
+			sprite, err := m.scanSprite(fsys, themeDir)
+			if err != nil {
+				return nil, err
+			}
+			frames[t] = sprite
+			continue
+		}
+
+		available := pickFrames(entries)
+		names := available
+		if len(m.Frames) > 0 {
+			names = make([]string, len(m.Frames))
+			for i, frame := range m.Frames {
+				name, ok := resolveFrame(available, entries, frame.File)
+				if !ok {
+					return nil, fmt.Errorf("%s: frame %s not found in %s", ManifestFile, frame.File, themeDir)
+				}
+				names[i] = name
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		for i, name := range names {
+			names[i] = path.Join(themeDir, name)
+		}
+		frames[t] = themeFrames{paths: names}
+	}
+	return frames, nil
+}
+
+// 读取精灵图的尺寸，补全排列方式并检查单元格数和每帧尺寸，此时不解码像素
+func (m *Manifest) scanSprite(fsys fs.FS, themeDir string) (themeFrames, error) {
+	name := path.Join(themeDir, m.Sprite.File)
+	file, err := fsys.Open(name)
+	if err != nil {
+		return themeFrames{}, fmt.Errorf("%s: sprite %s not found in %s", ManifestFile, m.Sprite.File, themeDir)
+	}
+	defer func() { _ = file.Close() }()
+
+	config, err := png.DecodeConfig(file)
+	if err != nil {
+		return themeFrames{}, fmt.Errorf("%s: sprite %s: %w", ManifestFile, name, err)
+	}
+	size := image.Pt(config.Width, config.Height)
+	layout, err := SpriteLayout{
+		Columns: m.Sprite.Columns,
+		Rows:    m.Sprite.Rows,
+		Frames:  m.Sprite.Frames,
+	}.resolve(image.Rectangle{Max: size})
+	if err == nil {
+		err = checkSprite(size, layout)
+	}
+	if err != nil {
+		return themeFrames{}, fmt.Errorf("%s: sprite %s: %w", ManifestFile, name, err)
+	}
+	return themeFrames{sprite: name, layout: layout}, nil
+}
+
+// 查找清单中的帧文件，没有扩展名时使用按平台选出的格式
+func resolveFrame(available []string, entries []fs.DirEntry, file string) (string, bool) {
+	if path.Ext(file) == "" {
+		for _, name := range available {
+			if strings.TrimSuffix(name, path.Ext(name)) == file {
+				return name, true
+			}
+		}
+		return "", false
+	}
+	ok := slices.ContainsFunc(entries, func(e fs.DirEntry) bool { return !e.IsDir() && e.Name() == file })
+	return file, ok
+}
@@ -0,0 +1,287 @@
+package resource
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+)
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+name: Mascot
+author: Our Team
+frames:
+  - file: run_b.png
+  - file: run_a.png
+    duration: 3
+themes:
+  light: day
+min_speed: 2
+max_speed: 10
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest: %v", err)
+	}
+	if m.Name != "Mascot" || m.Author != "Our Team" || len(m.Frames) != 2 || m.Themes["light"] != "day" {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+
+	timing := m.Timing()
+	if !slices.Equal(timing.Durations, []float64{1, 3}) || timing.MinSpeed != 2 || timing.MaxSpeed != 10 {
+		t.Fatalf("unexpected timing %+v", timing)
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{
+			name:     "unknown key",
+			manifest: "name: x\nspeed: 3\n",
+			want:     []string{"speed"},
+		},
+		{
+			name: "all problems are reported",
+			manifest: `
+frames:
+  - file: ../escape.png
+  - file: frame.gif
+  - duration: -1
+themes:
+  sepia: sepia
+  dark: /abs
+min_speed: 5
+max_speed: 3
+`,
+			want: []string{
+				`frames[0]: file "../escape.png" must be a plain file name`,
+				`frames[1]: file "frame.gif" must be one of`,
+				"frames[2]: file is required",
+				"frames[2]: duration -1 must not be negative",
+				`unknown theme "sepia"`,
+				`themes.dark: directory "/abs"`,
+				"min_speed 5 is greater than max_speed 3",
+			},
+		},
+		{
+			name:     "invalid sprite",
+			manifest: "sprite:\n  file: sheet.gif\n  columns: -1\nframes:\n  - file: a.png\n",
+			want: []string{
+				`sprite: file "sheet.gif" must be a .png image`,
+				"sprite: columns, rows and frames must not be negative",
+				"sprite and frames cannot be used together",
+			},
+		},
+		{
+			name:     "max speed too high",
+			manifest: "max_speed: 50\n",
+			want:     []string{"max_speed 50 must be between 0 and 20"},
+		},
+		{
+			name:     "min speed too high",
+			manifest: "min_speed: 50\n",
+			want:     []string{"min_speed 50 must be between 0 and 20", "min_speed 50 is greater than max_speed 20"},
+		},
+		{
+			name:     "min speed above max speed",
+			manifest: "min_speed: 12\nmax_speed: 8\n",
+			want:     []string{"min_speed 12 is greater than max_speed 8"},
+		},
+		{
+			name:     "negative min speed",
+			manifest: "min_speed: -1\n",
+			want:     []string{"min_speed -1 must be between 0 and 20"},
+		},
+		{
+			name:     "not a number",
+			manifest: "min_speed: .nan\nmax_speed: .nan\n",
+			want:     []string{"min_speed NaN must be between 0 and 20", "max_speed NaN must be between 0 and 20"},
+		},
+	}
+	for _, tt := range tests {
+		_, err := ParseManifest([]byte(tt.manifest))
+		if err == nil {
+			t.Errorf("%s: ParseManifest succeeded", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+			}
+		}
+	}
+}
+
+func TestLoadRunnersWithManifest(t *testing.T) {
+	user := fstest.MapFS{
+		"mascot/runner.yaml": file(`
+name: Team Mascot
+author: Our Team
+frames:
+  - file: run_b.png
+    duration: 2
+  - file: run_a.png
+themes:
+  light: day
+  dark: night
+`),
+		"mascot/day/run_a.png":   file("day-a"),
+		"mascot/day/run_b.png":   file("day-b"),
+		"mascot/day/unused.png":  file("day-unused"),
+		"mascot/night/run_a.png": file("night-a"),
+		"mascot/night/run_b.png": file("night-b"),
+
+		// 清单引用的帧不存在
+		"broken/runner.yaml":     file("frames:\n  - file: missing.png\n"),
+		"broken/light/frame.png": file("broken"),
+
+		// 清单无效
+		"invalid/runner.yaml":      file("max_speed: -1\n"),
+		"invalid/light/frame.png":  file("invalid"),
+		"plain/light/frame_0.png":  file("plain-0"),
+		"plain/light/frame_1.png":  file("plain-1"),
+		"plain/runner.yaml.bak":    file("ignored"),
+		"plain/light/runner.yaml":  file("ignored"),
+		"plain/dark/frame_0.ico":   file("plain-dark-0"),
+		"plain/dark/frame_0.ico.1": file("ignored"),
+	}
+
+	m := NewResourceManager(fstest.MapFS{})
+	if err := m.LoadRunners(user); err != nil {
+		t.Fatalf("LoadRunners: %v", err)
+	}
+
+	if got, want := m.Runners(), []RunnerType{"mascot", "plain"}; !slices.Equal(got, want) {
+		t.Fatalf("Runners() = %v, want %v", got, want)
+	}
+
+	info := m.RunnerInfo("mascot")
+	if info.Label != "Team Mascot" || info.Author != "Our Team" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if !slices.Equal(info.Timing.Durations, []float64{2, 1}) {
+		t.Fatalf("durations %v, want [2 1]", info.Timing.Durations)
+	}
+
+	tests := []struct {
+		runner RunnerType
+		theme  theme.Type
+		want   []string
+	}{
+		// 按清单中的顺序，不包含未列出的文件
+		{"mascot", theme.LightType, []string{"day-b", "day-a"}},
+		{"mascot", theme.DarkType, []string{"night-b", "night-a"}},
+		// 没有清单时按命名约定
+		{"plain", theme.LightType, []string{"plain-0", "plain-1"}},
+		{"plain", theme.DarkType, []string{"plain-dark-0"}},
+	}
+	for _, tt := range tests {
+		icons, err := m.LoadIcons(tt.runner, tt.theme)
+		if err != nil {
+			t.Fatalf("LoadIcons(%s, %s): %v", tt.runner, tt.theme, err)
+		}
+		got := make([]string, len(icons))
+		for i, icon := range icons {
+			got[i] = string(icon)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("LoadIcons(%s, %s) = %v, want %v", tt.runner, tt.theme, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSpriteRunner(t *testing.T) {
+	// 按帧排列纯色块拼成精灵图
+	tiles := func(columns, rows int, colors ...color.Color) []byte {
+		img := image.NewNRGBA(image.Rect(0, 0, 4*columns, 4*rows))
+		for i, c := range colors {
+			draw.Draw(img, image.Rect(i%columns*4, i/columns*4, i%columns*4+4, i/columns*4+4), image.NewUniform(c), image.Point{}, draw.Src)
+		}
+		var buf bytes.Buffer
+		_ = png.Encode(&buf, img)
+		return buf.Bytes()
+	}
+
+	user := fstest.MapFS{
+		// 横排，深色主题为竖排
+		"strip/runner.yaml":       file("sprite:\n  file: sheet.png\n"),
+		"strip/light/sheet.png":   {Data: sheet(red, blue, black)},
+		"strip/dark/sheet.png":    {Data: tiles(1, 2, blue, red)},
+		"strip/light/frame_0.png": file("ignored"),
+
+		// 网格，最后一行未填满
+		"grid/runner.yaml":     file("sprite:\n  file: sheet.png\n  columns: 2\n  rows: 2\n  frames: 3\n"),
+		"grid/light/sheet.png": {Data: tiles(2, 2, red, blue, black)},
+
+		// 尺寸无法切分
+		"uneven/runner.yaml":     file("sprite:\n  file: sheet.png\n  columns: 3\n"),
+		"uneven/light/sheet.png": {Data: sheet(red, blue)},
+
+		// 只有文件头，尺寸超出限制时不应解码
+		"huge/runner.yaml":      file("sprite:\n  file: sheet.png\n"),
+		"huge/light/sheet.png":  {Data: resizedPNGHeader(t, 300, 300)},
+		"cells/runner.yaml":     file("sprite:\n  file: sheet.png\n  columns: 2048\n"),
+		"cells/light/sheet.png": {Data: resizedPNGHeader(t, 2048, 1)},
+	}
+
+	m := NewResourceManager(fstest.MapFS{})
+	if err := m.LoadRunners(user); err != nil {
+		t.Fatalf("LoadRunners: %v", err)
+	}
+	if got, want := m.Runners(), []RunnerType{"grid", "strip"}; !slices.Equal(got, want) {
+		t.Fatalf("Runners() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		runner RunnerType
+		theme  theme.Type
+		want   []color.Color
+	}{
+		{"strip", theme.LightType, []color.Color{red, blue, black}},
+		{"strip", theme.DarkType, []color.Color{blue, red}},
+		// 网格为2列，第一行之后是第二行
+		{"grid", theme.LightType, []color.Color{red, blue, black}},
+		// 缺少的主题使用另一个主题的精灵图
+		{"grid", theme.DarkType, []color.Color{red, blue, black}},
+	}
+	for _, tt := range tests {
+		if got := m.GetIconCount(tt.runner, tt.theme); got != len(tt.want) {
+			t.Errorf("GetIconCount(%s, %s) = %d, want %d", tt.runner, tt.theme, got, len(tt.want))
+		}
+		icons, err := m.LoadIcons(tt.runner, tt.theme)
+		if err != nil {
+			t.Fatalf("LoadIcons(%s, %s): %v", tt.runner, tt.theme, err)
+		}
+		if len(icons) != len(tt.want) {
+			t.Fatalf("LoadIcons(%s, %s) returned %d frames, want %d", tt.runner, tt.theme, len(icons), len(tt.want))
+		}
+		for i, icon := range icons {
+			img, err := DecodeIcon(icon)
+			if err != nil {
+				t.Fatalf("%s/%s frame %d: %v", tt.runner, tt.theme, i, err)
+			}
+			b := img.Bounds()
+			if got := img.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2); !sameColor(got, tt.want[i]) {
+				t.Errorf("%s/%s frame %d is %v, want %v", tt.runner, tt.theme, i, got, tt.want[i])
+			}
+		}
+	}
+
+	// 切分结果被缓存
+	first, _ := m.LoadIcons("strip", theme.LightType)
+	second, _ := m.LoadIcons("strip", theme.LightType)
+	if &first[0][0] != &second[0][0] {
+		t.Error("sprite frames were sliced again instead of using the cache")
+	}
+}
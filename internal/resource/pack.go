@@ -0,0 +1,212 @@
+package resource
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PackExtension 角色包的文件扩展名
+//
+// 角色包是一个zip文件，根目录下包含：
+//
+//	runner.yaml       角色清单，必需
+//	LICENSE           素材的许可证，必需，也可以是LICENSE.txt、LICENSE.md或COPYING
+//	light/, dark/     各主题的帧文件或精灵图，目录可以在清单的themes中修改
+//
+// 安装前会按Manager.Verify的规则校验所有帧
+const PackExtension = ".runcat"
+
+const (
+	// 角色包中允许的最多文件数
+	maxPackFiles = 1024
+	// 角色包解压后的最大总大小
+	maxPackSize = 64 << 20
+)
+
+// 可以作为许可证的文件名，不区分大小写
+var licenseFiles = []string{"license", "license.txt", "license.md", "copying"}
+
+// InstallPack 校验角色包并解压到自定义角色目录下的name子目录，随后注册该角色
+// 同名角色已安装时，只有force为true才会覆盖
+func (m *Manager) InstallPack(r io.ReaderAt, size int64, name RunnerType, force bool) (RunnerInfo, error) {
+	dir, err := m.runnerDir(name)
+	if err != nil {
+		return RunnerInfo{}, err
+	}
+
+	pack, err := openPack(r, size)
+	if err != nil {
+		return RunnerInfo{}, err
+	}
+
+	if _, err := os.Stat(dir); err == nil && !force {
+		return RunnerInfo{}, fmt.Errorf("runner %s is already installed, use force to replace it", name)
+	}
+
+	// 先解压到隐藏的临时目录，完成后再替换，避免留下不完整的角色
+	userDir := filepath.Dir(dir)
+	if err := os.MkdirAll(userDir, 0o755); err != nil {
+		return RunnerInfo{}, err
+	}
+	tmp, err := os.MkdirTemp(userDir, ".install-*")
+	if err != nil {
+		return RunnerInfo{}, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	if err := extractPack(pack, tmp); err != nil {
+		return RunnerInfo{}, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return RunnerInfo{}, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return RunnerInfo{}, err
+	}
+
+	source, err := scanRunner(os.DirFS(userDir), string(name))
+	if err != nil {
+		return RunnerInfo{}, err
+	}
+	if source.label == "" {
+		source.label = string(name)
+	}
+	m.register(name, source)
+	return m.RunnerInfo(name), nil
+}
+
+// InstalledRunners 返回已注册的自定义角色，按注册顺序排列
+func (m *Manager) InstalledRunners() []RunnerType {
+	return slices.DeleteFunc(m.Runners(), func(r RunnerType) bool {
+		return slices.Contains(supportedRunners, r)
+	})
+}
+
+// RemoveRunner 删除自定义角色目录下的name子目录并注销该角色
+func (m *Manager) RemoveRunner(name RunnerType) error {
+	dir, err := m.runnerDir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("runner %s is not installed", name)
+		}
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	m.unregister(name)
+	return nil
+}
+
+// 返回自定义角色的目录，拒绝内置角色和不安全的名称
+func (m *Manager) runnerDir(name RunnerType) (string, error) {
+	m.mu.RLock()
+	userDir := m.userDir
+	m.mu.RUnlock()
+
+	switch {
+	case userDir == "":
+		return "", errors.New("custom runners directory is not set")
+	case slices.Contains(supportedRunners, name):
+		return "", fmt.Errorf("runner %s is built in", name)
+	case name == "" || strings.HasPrefix(string(name), ".") || !fs.ValidPath(string(name)) ||
+		strings.ContainsAny(string(name), `/\:`):
+		return "", fmt.Errorf("invalid runner name %q", name)
+	}
+	return filepath.Join(userDir, string(name)), nil
+}
+
+// 打开并校验角色包：文件路径、清单、许可证以及帧的尺寸
+func openPack(r io.ReaderAt, size int64) (*zip.Reader, error) {
+	pack, err := zip.NewReader(r, size)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return nil, fmt.Errorf("invalid runner pack: %w", err)
+	}
+	if len(pack.File) > maxPackFiles {
+		return nil, fmt.Errorf("invalid runner pack: more than %d files", maxPackFiles)
+	}
+
+	var total uint64
+	hasLicense := false
+	for _, f := range pack.File {
+		name := strings.TrimSuffix(f.Name, "/")
+		if strings.Contains(f.Name, `\`) || !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid runner pack: unsafe path %q", f.Name)
+		}
+		if !f.Mode().IsRegular() && !f.Mode().IsDir() {
+			return nil, fmt.Errorf("invalid runner pack: %s is not a regular file", f.Name)
+		}
+		if total += f.UncompressedSize64; total > maxPackSize {
+			return nil, fmt.Errorf("invalid runner pack: larger than %d MiB", maxPackSize>>20)
+		}
+		if slices.Contains(licenseFiles, strings.ToLower(name)) {
+			hasLicense = true
+		}
+	}
+
+	if _, err := fs.Stat(pack, ManifestFile); err != nil {
+		return nil, fmt.Errorf("invalid runner pack: missing %s", ManifestFile)
+	}
+	if !hasLicense {
+		return nil, errors.New("invalid runner pack: missing LICENSE")
+	}
+
+	source, err := scanRunner(pack, ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid runner pack: %w", err)
+	}
+	if errs := verifySource(source); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid runner pack: %w", errors.Join(errs...))
+	}
+	return pack, nil
+}
+
+// 将角色包中的文件解压到dir
+func extractPack(pack *zip.Reader, dir string) error {
+	for _, f := range pack.File {
+		target := filepath.Join(dir, filepath.FromSlash(path.Clean(f.Name)))
+		if f.Mode().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 解压单个文件，zip.Reader会校验实际大小和CRC
+func extractFile(f *zip.File, target string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return dst.Close()
+}
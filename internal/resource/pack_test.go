@@ -0,0 +1,164 @@
+package resource
+
+import (
+	"archive/zip"
+	"bytes"
+	"image/png"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+)
+
+// 将文件打包为zip
+func buildPack(t *testing.T, files map[string][]byte) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// 编码纯色PNG帧
+func pngFrame(size int) []byte {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, solid(size, size, red))
+	return buf.Bytes()
+}
+
+// 一个有效角色包的文件
+func validPack() map[string][]byte {
+	return map[string][]byte{
+		"runner.yaml":       []byte("name: Packed\nauthor: Someone\n"),
+		"LICENSE":           []byte("CC0"),
+		"light/frame_0.png": pngFrame(16),
+		"light/frame_1.png": pngFrame(16),
+		"dark/frame_0.png":  pngFrame(16),
+		"dark/frame_1.png":  pngFrame(16),
+	}
+}
+
+func TestInstallPack(t *testing.T) {
+	dir := t.TempDir()
+	m := NewResourceManager(fstest.MapFS{})
+	if err := m.LoadUserRunners(dir); err != nil {
+		t.Fatalf("LoadUserRunners: %v", err)
+	}
+
+	pack := buildPack(t, validPack())
+	info, err := m.InstallPack(pack, pack.Size(), "packed", false)
+	if err != nil {
+		t.Fatalf("InstallPack: %v", err)
+	}
+	if info.Label != "Packed" || info.Author != "Someone" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if got := m.InstalledRunners(); !slices.Equal(got, []RunnerType{"packed"}) {
+		t.Fatalf("InstalledRunners() = %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "packed", "LICENSE")); err != nil {
+		t.Fatalf("license was not extracted: %v", err)
+	}
+	if got := m.GetIconCount("packed", theme.LightType); got != 2 {
+		t.Fatalf("GetIconCount = %d, want 2", got)
+	}
+
+	// 已安装时需要force
+	if _, err := m.InstallPack(pack, pack.Size(), "packed", false); err == nil {
+		t.Fatal("reinstalling without force succeeded")
+	}
+	if _, err := m.InstallPack(pack, pack.Size(), "packed", true); err != nil {
+		t.Fatalf("reinstalling with force: %v", err)
+	}
+
+	// 重新加载后依然可用
+	reloaded := NewResourceManager(fstest.MapFS{})
+	if err := reloaded.LoadUserRunners(dir); err != nil || !reloaded.HasRunner("packed") {
+		t.Fatalf("installed runner not found after reload: %v", err)
+	}
+
+	if err := m.RemoveRunner("packed"); err != nil {
+		t.Fatalf("RemoveRunner: %v", err)
+	}
+	if m.HasRunner("packed") || len(m.InstalledRunners()) != 0 {
+		t.Fatal("removed runner is still registered")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "packed")); !os.IsNotExist(err) {
+		t.Fatalf("runner directory still exists: %v", err)
+	}
+	if err := m.RemoveRunner("packed"); err == nil {
+		t.Fatal("removing a missing runner succeeded")
+	}
+
+	// 没有留下临时目录
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("unexpected leftovers %v", entries)
+	}
+}
+
+func TestInstallPackErrors(t *testing.T) {
+	// 文件头声明的尺寸远大于实际数据的帧，解码前必须被拒绝
+	hostilePNG := resizedPNGHeader(t, 1<<20, 1<<20)
+	hostileICO := singleICO(icoBitmap(0x7fffffff, 2, 2, 16))
+
+	tests := []struct {
+		name   string
+		runner RunnerType
+		modify func(files map[string][]byte)
+		want   string
+	}{
+		{"parent path", "evil", func(f map[string][]byte) { f["../evil.png"] = nil }, "unsafe path"},
+		{"nested parent path", "evil", func(f map[string][]byte) { f["light/../../evil.png"] = nil }, "unsafe path"},
+		{"absolute path", "evil", func(f map[string][]byte) { f["/etc/evil"] = nil }, "unsafe path"},
+		{"backslash path", "evil", func(f map[string][]byte) { f[`..\evil.png`] = nil }, "unsafe path"},
+		{"missing license", "packed", func(f map[string][]byte) { delete(f, "LICENSE") }, "missing LICENSE"},
+		{"missing manifest", "packed", func(f map[string][]byte) { delete(f, "runner.yaml") }, "missing runner.yaml"},
+		{"mixed frame sizes", "packed", func(f map[string][]byte) { f["light/frame_1.png"] = pngFrame(32) }, "differs from 16x16"},
+		{"frame too large", "packed", func(f map[string][]byte) {
+			f["light/frame_0.png"] = pngFrame(300)
+			f["light/frame_1.png"] = pngFrame(300)
+		}, "must be between 1 and 256"},
+		{"hostile png frame", "packed", func(f map[string][]byte) { f["light/frame_1.png"] = hostilePNG }, "frame size 1048576x1048576"},
+		{"hostile ico frame", "packed", func(f map[string][]byte) {
+			delete(f, "dark/frame_1.png")
+			f["dark/frame_1.ico"] = hostileICO
+		}, "invalid bitmap header"},
+		{"undecodable frame", "packed", func(f map[string][]byte) { f["light/frame_1.png"] = []byte("junk") }, "frame_1.png"},
+		{"invalid manifest", "packed", func(f map[string][]byte) { f["runner.yaml"] = []byte("speed: 1\n") }, "speed"},
+		{"built-in name", RunnerCat, func(map[string][]byte) {}, "built in"},
+		{"unsafe name", "../cat", func(map[string][]byte) {}, "invalid runner name"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		m := NewResourceManager(fstest.MapFS{})
+		_ = m.LoadUserRunners(dir)
+
+		files := validPack()
+		tt.modify(files)
+		pack := buildPack(t, files)
+		_, err := m.InstallPack(pack, pack.Size(), tt.runner, false)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v does not mention %q", tt.name, err, tt.want)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s: left files behind: %v", tt.name, entries)
+		}
+	}
+}
@@ -2,11 +2,22 @@ package resource
 
 import (
 	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/eatmoreapple/go-runcat/internal/animation"
 	"github.com/eatmoreapple/go-runcat/internal/theme"
 )
 
@@ -25,109 +36,416 @@ var supportedRunners = []RunnerType{
 	RunnerHorse,
 }
 
+// 内置角色在菜单中显示的名称
+var runnerLabels = map[RunnerType]string{
+	RunnerCat:    "Cat",
+	RunnerParrot: "Parrot",
+	RunnerHorse:  "Horse",
+}
+
+// 支持的主题变体
+var supportThemes = []theme.Type{
+	theme.LightType,
+	theme.DarkType,
+}
+
+// 支持的帧文件扩展名，同名的帧按此顺序选择；Windows托盘只支持ICO
+var frameExtensions = func() []string {
+	if runtime.GOOS == "windows" {
+		return []string{".ico", ".png"}
+	}
+	return []string{".png", ".ico"}
+}()
+
+// runnerSource 角色资源的位置和帧列表
+type runnerSource struct {
+	// 资源所在的文件系统
+	fs fs.FS
+	// 角色目录，包含light和dark子目录
+	dir string
+	// 各主题的帧来源
+	frames map[theme.Type]themeFrames
+	// 菜单中显示的名称
+	label string
+	// 作者
+	author string
+	// 动画节奏
+	timing animation.Timing
+}
+
+// themeFrames 一个主题的帧来源：逐帧文件或一张精灵图
+type themeFrames struct {
+	// 帧文件路径，按播放顺序排列
+	paths []string
+	// 精灵图路径，非空时代替paths
+	sprite string
+	// 精灵图的排列方式，已补全行列和帧数
+	layout SpriteLayout
+}
+
+// 帧数
+func (f themeFrames) count() int {
+	if f.sprite != "" {
+		return f.layout.Frames
+	}
+	return len(f.paths)
+}
+
+// RunnerInfo 角色的元数据
+type RunnerInfo struct {
+	// 菜单中显示的名称
+	Label string
+	// 作者，未知时为空
+	Author string
+	// 每帧的相对时长和速度范围
+	Timing animation.Timing
+}
+
 // Manager 资源管理器
 type Manager struct {
 	// 嵌入的资源文件
 	fs fs.FS
+	// 保护icons和runners的互斥锁
+	mu sync.RWMutex
 	// 缓存的图标资源
 	icons map[string][][]byte
-	// 图标计数
-	iconCounts map[RunnerType]map[theme.Type]int
+	// 已注册的角色
+	runners map[RunnerType]*runnerSource
+	// 角色的注册顺序，内置角色在前
+	order []RunnerType
+	// 自定义角色所在的目录，角色包安装到这里
+	userDir string
 }
 
 // NewResourceManager 创建一个新的资源管理器
 func NewResourceManager(fs fs.FS) *Manager {
 	rm := &Manager{
-		fs:         fs,
-		icons:      make(map[string][][]byte),
-		iconCounts: make(map[RunnerType]map[theme.Type]int),
+		fs:      fs,
+		icons:   make(map[string][][]byte),
+		runners: make(map[RunnerType]*runnerSource),
 	}
 
-	// 初始化图标计数
-	rm.initIconCounts()
+	// 注册内置角色
+	rm.initBuiltinRunners()
 
 	return rm
 }
 
-// 初始化图标计数
-func (m *Manager) initIconCounts() {
+// 注册内置角色
+func (m *Manager) initBuiltinRunners() {
 	for _, runner := range supportedRunners {
-		m.iconCounts[runner] = make(map[theme.Type]int)
+		// note: do not use filepath.Join here
+		source, err := scanRunner(m.fs, path.Join("assets", string(runner)))
+		if err != nil {
+			log.Printf("Failed to scan built-in runner %s: %v", runner, err)
+			source = &runnerSource{fs: m.fs, frames: make(map[theme.Type]themeFrames)}
+		}
+		if source.label == "" {
+			source.label = runnerLabels[runner]
+		}
+		m.register(runner, source)
 	}
+}
 
-	var supportThemes = []theme.Type{
-		theme.LightType,
-		theme.DarkType,
+// UserRunnersDir 返回用户自定义角色所在的目录，即 <UserConfigDir>/go-runcat/runners
+func UserRunnersDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
 	}
+	return filepath.Join(configDir, "go-runcat", "runners"), nil
+}
 
-	// 尝试从文件系统中获取实际计数
-	for runner := range m.iconCounts {
-		for _, t := range supportThemes {
-			// note: do not use filepath.Join here
-			pattern := fmt.Sprintf("assets/%s/%s/*.ico", runner, t)
-			files, err := fs.Glob(m.fs, pattern)
-			if err == nil && len(files) > 0 {
-				m.iconCounts[runner][t] = len(files)
-			}
+// LoadUserRunners 加载dir中的自定义角色，并将dir作为安装和删除角色包的目录
+// dir不存在时返回fs.ErrNotExist，之后仍可以安装角色包
+func (m *Manager) LoadUserRunners(dir string) error {
+	m.mu.Lock()
+	m.userDir = dir
+	m.mu.Unlock()
+
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	return m.LoadRunners(os.DirFS(dir))
+}
+
+// LoadRunners 扫描fsys中的 <name>/{light,dark}/ 目录，将其注册为额外的角色
+// 与内置角色同名或没有任何帧的目录会被跳过并记录日志
+func (m *Manager) LoadRunners(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		runner := RunnerType(entry.Name())
+		if slices.Contains(supportedRunners, runner) {
+			log.Printf("Skipping custom runner %s: conflicts with a built-in runner", runner)
+			continue
+		}
+
+		source, err := scanRunner(fsys, entry.Name())
+		if err != nil {
+			log.Printf("Skipping custom runner %s: %v", runner, err)
+			continue
 		}
+		if len(source.frames) == 0 {
+			log.Printf("Skipping custom runner %s: no frames in light/ or dark/", runner)
+			continue
+		}
+		if source.label == "" {
+			source.label = entry.Name()
+		}
+		m.register(runner, source)
+	}
+	return nil
+}
+
+// 注销角色并清除缓存
+func (m *Manager) unregister(runner RunnerType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.runners, runner)
+	m.order = slices.DeleteFunc(m.order, func(r RunnerType) bool { return r == runner })
+	for _, t := range supportThemes {
+		delete(m.icons, cacheKey(runner, t))
 	}
 }
 
+// 注册角色，同名角色会替换之前的注册并清除缓存
+func (m *Manager) register(runner RunnerType, source *runnerSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.runners[runner]; !ok {
+		m.order = append(m.order, runner)
+	}
+	m.runners[runner] = source
+	for _, t := range supportThemes {
+		delete(m.icons, cacheKey(runner, t))
+	}
+}
+
+// 扫描角色目录，存在runner.yaml时按清单加载，否则按命名约定扫描light和dark子目录
+func scanRunner(fsys fs.FS, dir string) (*runnerSource, error) {
+	manifest := &Manifest{}
+	data, err := fs.ReadFile(fsys, path.Join(dir, ManifestFile))
+	switch {
+	case err == nil:
+		if manifest, err = ParseManifest(data); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	frames, err := manifest.scanFrames(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &runnerSource{
+		fs:     fsys,
+		dir:    dir,
+		frames: frames,
+		label:  manifest.Name,
+		author: manifest.Author,
+		timing: manifest.Timing(),
+	}, nil
+}
+
+// 从目录项中选出帧文件，同名的帧只保留优先的格式
+func pickFrames(entries []fs.DirEntry) []string {
+	best := make(map[string]string)
+	for _, entry := range entries {
+		ext := strings.ToLower(path.Ext(entry.Name()))
+		if entry.IsDir() || !slices.Contains(frameExtensions, ext) {
+			continue
+		}
+		stem := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		if prev, ok := best[stem]; ok && frameRank(prev) <= frameRank(entry.Name()) {
+			continue
+		}
+		best[stem] = entry.Name()
+	}
+
+	names := make([]string, 0, len(best))
+	for _, name := range best {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, compareFrameNames)
+	return names
+}
+
+// 帧文件格式的优先级，越小越优先
+func frameRank(name string) int {
+	return slices.Index(frameExtensions, strings.ToLower(path.Ext(name)))
+}
+
+// 按文件名末尾的数字排序，使 frame_10 排在 frame_9 之后
+func compareFrameNames(a, b string) int {
+	prefixA, numA := splitFrameName(a)
+	prefixB, numB := splitFrameName(b)
+	if c := strings.Compare(prefixA, prefixB); c != 0 {
+		return c
+	}
+	if numA != numB {
+		return numA - numB
+	}
+	return strings.Compare(a, b)
+}
+
+// 拆分文件名（不含扩展名）为前缀和末尾的数字，没有数字时返回-1
+func splitFrameName(name string) (string, int) {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+	n, err := strconv.Atoi(name[i:])
+	if err != nil {
+		return name, -1
+	}
+	return name[:i], n
+}
+
+// Runners 返回所有可用的角色，内置角色在前，自定义角色按注册顺序排列
+func (m *Manager) Runners() []RunnerType {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runners := make([]RunnerType, 0, len(m.order))
+	for _, runner := range m.order {
+		if len(m.runners[runner].frames) > 0 {
+			runners = append(runners, runner)
+		}
+	}
+	return runners
+}
+
+// HasRunner 检查角色是否可用
+func (m *Manager) HasRunner(runner RunnerType) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	source, ok := m.runners[runner]
+	return ok && len(source.frames) > 0
+}
+
+// RunnerLabel 返回角色在菜单中显示的名称
+func (m *Manager) RunnerLabel(runner RunnerType) string {
+	return m.RunnerInfo(runner).Label
+}
+
+// RunnerInfo 返回角色的元数据，未知角色只包含名称
+func (m *Manager) RunnerInfo(runner RunnerType) RunnerInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	info := RunnerInfo{Label: string(runner)}
+	if source, ok := m.runners[runner]; ok {
+		info.Label = cmp.Or(source.label, info.Label)
+		info.Author = source.author
+		info.Timing = source.timing
+	}
+	return info
+}
+
+// 图标缓存键
+func cacheKey(runner RunnerType, themeType theme.Type) string {
+	return fmt.Sprintf("%s_%s", themeType, runner)
+}
+
+// 返回角色在指定主题下的帧来源，缺少该主题时使用另一个主题的帧
+func (m *Manager) themeSource(runner RunnerType, themeType theme.Type) (fs.FS, themeFrames, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	source, ok := m.runners[runner]
+	if !ok {
+		return nil, themeFrames{}, false
+	}
+	if frames, ok := source.frames[themeType]; ok && frames.count() > 0 {
+		return source.fs, frames, true
+	}
+	for _, t := range supportThemes {
+		if frames, ok := source.frames[t]; ok && frames.count() > 0 {
+			return source.fs, frames, true
+		}
+	}
+	return nil, themeFrames{}, false
+}
+
 // LoadIcons 加载指定角色和主题的图标
 func (m *Manager) LoadIcons(runner RunnerType, themeType theme.Type) ([][]byte, error) {
 	// 生成缓存键
-	key := fmt.Sprintf("%s_%s", themeType, runner)
+	key := cacheKey(runner, themeType)
 
 	// 检查缓存
-	if icons, ok := m.icons[key]; ok {
+	m.mu.RLock()
+	icons, ok := m.icons[key]
+	m.mu.RUnlock()
+	if ok {
 		return icons, nil
 	}
 
-	// 获取图标数量
-	count := m.GetIconCount(runner, themeType)
-	if count == 0 {
+	// 获取帧来源
+	fsys, frames, ok := m.themeSource(runner, themeType)
+	if !ok {
 		return nil, fmt.Errorf("no icons found for runner: %s, %s", runner, themeType)
 	}
 
 	// 加载图标
-	readFromFs := func(path string) ([]byte, error) {
-		file, err := m.fs.Open(path)
+	readFromFs := func(name string) ([]byte, error) {
+		file, err := fsys.Open(name)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read icon %s: %w", path, err)
+			return nil, fmt.Errorf("failed to read icon %s: %w", name, err)
 		}
 		defer func() { _ = file.Close() }()
 		return io.ReadAll(file)
 	}
 
-	icons := make([][]byte, count)
-
-	// 遍历图标索引
-	for i := 0; i < count; i++ {
-		// 构建资源路径
-		path := fmt.Sprintf("assets/%s/%s/%s_%s_%d.ico", runner, themeType, themeType, strings.ToLower(string(runner)), i)
-
-		// 读取资源文件
-		data, err := readFromFs(path)
+	if frames.sprite != "" {
+		// 切分精灵图并编码为托盘需要的格式
+		data, err := readFromFs(frames.sprite)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read icon %s: %w", path, err)
+			return nil, err
+		}
+		if icons, err = sliceSpriteIcons(data, frames.layout); err != nil {
+			return nil, fmt.Errorf("failed to load sprite %s: %w", frames.sprite, err)
 		}
+	} else {
+		icons = make([][]byte, len(frames.paths))
 
-		icons[i] = data
+		// 遍历帧文件
+		for i, name := range frames.paths {
+			// 读取资源文件
+			data, err := readFromFs(name)
+			if err != nil {
+				return nil, err
+			}
+
+			// 转换为托盘支持的格式
+			if icons[i], err = trayIcon(data); err != nil {
+				return nil, fmt.Errorf("failed to convert icon %s: %w", name, err)
+			}
+		}
 	}
 
 	// 缓存图标
+	m.mu.Lock()
 	m.icons[key] = icons
+	m.mu.Unlock()
 
 	return icons, nil
 }
 
 // GetIconCount 获取指定角色的图标数量
 func (m *Manager) GetIconCount(runner RunnerType, themeType theme.Type) int {
-	count, ok := m.iconCounts[runner]
-	if !ok {
-		return 0
-	}
-	return count[themeType]
+	_, frames, _ := m.themeSource(runner, themeType)
+	return frames.count()
 }
 
 // GetIcon 获取指定角色、主题和索引的图标
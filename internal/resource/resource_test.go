@@ -0,0 +1,101 @@
+package resource
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+)
+
+func file(data string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(data)}
+}
+
+func TestLoadRunners(t *testing.T) {
+	assets := fstest.MapFS{
+		"assets/cat/light/light_cat_0.ico": file("cat-light-0"),
+		"assets/cat/dark/dark_cat_0.ico":   file("cat-dark-0"),
+	}
+	user := fstest.MapFS{
+		// 末尾数字按数值排序
+		"mascot/light/frame_10.png": file("m10"),
+		"mascot/light/frame_2.png":  file("m2"),
+		"mascot/light/frame_1.png":  file("m1"),
+		"mascot/light/notes.txt":    file("ignored"),
+		// 只有深色主题
+		"night/dark/0.ico": file("n0"),
+		// 与内置角色同名
+		"cat/light/0.ico": file("fake-cat"),
+		// 没有帧
+		"empty/light/readme.md": file(""),
+		".hidden/light/0.ico":   file("h0"),
+	}
+
+	m := NewResourceManager(assets)
+	if err := m.LoadRunners(user); err != nil {
+		t.Fatalf("LoadRunners: %v", err)
+	}
+
+	// 内置角色在前，缺少帧的内置角色不可用
+	want := []RunnerType{RunnerCat, "mascot", "night"}
+	if got := m.Runners(); !slices.Equal(got, want) {
+		t.Fatalf("Runners() = %v, want %v", got, want)
+	}
+	if !m.HasRunner("mascot") || m.HasRunner("empty") || m.HasRunner(RunnerHorse) {
+		t.Fatal("HasRunner reports wrong availability")
+	}
+	if got := m.RunnerLabel(RunnerCat); got != "Cat" {
+		t.Fatalf("RunnerLabel(cat) = %q, want Cat", got)
+	}
+
+	tests := []struct {
+		runner RunnerType
+		theme  theme.Type
+		want   []string
+	}{
+		{RunnerCat, theme.LightType, []string{"cat-light-0"}},
+		{"mascot", theme.LightType, []string{"m1", "m2", "m10"}},
+		// 缺少的主题使用另一个主题的帧
+		{"mascot", theme.DarkType, []string{"m1", "m2", "m10"}},
+		{"night", theme.LightType, []string{"n0"}},
+	}
+	for _, tt := range tests {
+		icons, err := m.LoadIcons(tt.runner, tt.theme)
+		if err != nil {
+			t.Fatalf("LoadIcons(%s, %s): %v", tt.runner, tt.theme, err)
+		}
+		got := make([]string, len(icons))
+		for i, icon := range icons {
+			got[i] = string(icon)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("LoadIcons(%s, %s) = %v, want %v", tt.runner, tt.theme, got, tt.want)
+		}
+	}
+
+	if _, err := m.LoadIcons("missing", theme.LightType); err == nil {
+		t.Error("LoadIcons for an unknown runner succeeded")
+	}
+}
+
+func TestPickFrames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"frame_0.ico":  file(""),
+		"frame_0.png":  file(""),
+		"frame_1.ICO":  file(""),
+		"frame_10.png": file(""),
+		"frame_2.png":  file(""),
+		"readme.txt":   file(""),
+	}
+	entries, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同名的帧只保留当前平台优先的格式
+	want := []string{"frame_0" + frameExtensions[0], "frame_1.ICO", "frame_2.png", "frame_10.png"}
+	if got := pickFrames(entries); !slices.Equal(got, want) {
+		t.Fatalf("pickFrames = %v, want %v", got, want)
+	}
+}
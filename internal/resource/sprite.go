@@ -0,0 +1,185 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"runtime"
+)
+
+// SpriteLayout 精灵图中帧的排列方式
+type SpriteLayout struct {
+	// 列数，为0时根据图像尺寸推断
+	Columns int
+	// 行数，为0时根据图像尺寸推断
+	Rows int
+	// 帧数，最后一行未填满时使用；为0时为Columns*Rows
+	Frames int
+}
+
+// 补全排列方式：未指定时按正方形帧推断为横排或竖排
+func (l SpriteLayout) resolve(bounds image.Rectangle) (SpriteLayout, error) {
+	w, h := bounds.Dx(), bounds.Dy()
+	switch {
+	case l.Columns > 0 && l.Rows > 0:
+	case l.Columns > 0:
+		l.Rows = 1
+	case l.Rows > 0:
+		l.Columns = 1
+	case w >= h && h > 0 && w%h == 0:
+		l.Columns, l.Rows = w/h, 1
+	case h > w && w > 0 && h%w == 0:
+		l.Columns, l.Rows = 1, h/w
+	default:
+		return l, fmt.Errorf("sprite: cannot infer layout of a %dx%d sheet, specify columns and rows", w, h)
+	}
+
+	if w%l.Columns != 0 || h%l.Rows != 0 {
+		return l, fmt.Errorf("sprite: %dx%d sheet is not divisible into %d columns and %d rows", w, h, l.Columns, l.Rows)
+	}
+	if l.Frames == 0 {
+		l.Frames = l.Columns * l.Rows
+	}
+	if l.Frames < 0 || l.Frames > l.Columns*l.Rows {
+		return l, fmt.Errorf("sprite: %d frames do not fit into %d columns and %d rows", l.Frames, l.Columns, l.Rows)
+	}
+	return l, nil
+}
+
+// 检查精灵图的单元格数和每帧尺寸，在解码像素之前调用
+func checkSprite(sheet image.Point, layout SpriteLayout) error {
+	if cells := layout.Columns * layout.Rows; cells > maxSpriteCells {
+		return fmt.Errorf("sprite: %d cells are more than %d", cells, maxSpriteCells)
+	}
+	if size := image.Pt(sheet.X/layout.Columns, sheet.Y/layout.Rows); !validFrameSize(size) {
+		return fmt.Errorf("sprite: %dx%d frames must be between 1 and %d pixels", size.X, size.Y, MaxFrameSize)
+	}
+	return nil
+}
+
+// SliceSprite 按行优先顺序将精灵图切分为帧，支持横排、竖排和网格排列
+func SliceSprite(sheet image.Image, layout SpriteLayout) ([]image.Image, error) {
+	bounds := sheet.Bounds()
+	layout, err := layout.resolve(bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := bounds.Dx()/layout.Columns, bounds.Dy()/layout.Rows
+	frames := make([]image.Image, layout.Frames)
+	for i := range frames {
+		x := bounds.Min.X + i%layout.Columns*w
+		y := bounds.Min.Y + i/layout.Columns*h
+		frame := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(frame, frame.Bounds(), sheet, image.Pt(x, y), draw.Src)
+		frames[i] = frame
+	}
+	return frames, nil
+}
+
+// 切分精灵图，并将每帧编码为托盘需要的格式
+// 文件可能在扫描之后被替换，解码之前重新检查尺寸
+func sliceSpriteIcons(data []byte, layout SpriteLayout) ([][]byte, error) {
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	size := image.Pt(config.Width, config.Height)
+	if layout, err = layout.resolve(image.Rectangle{Max: size}); err != nil {
+		return nil, err
+	}
+	if err := checkSprite(size, layout); err != nil {
+		return nil, err
+	}
+
+	sheet, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	frames, err := SliceSprite(sheet, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	icons := make([][]byte, len(frames))
+	for i, frame := range frames {
+		if icons[i], err = encodeTrayIcon(frame); err != nil {
+			return nil, err
+		}
+	}
+	return icons, nil
+}
+
+// 将图像编码为托盘需要的格式：Windows使用ICO，其他平台使用PNG
+func encodeTrayIcon(img image.Image) ([]byte, error) {
+	if runtime.GOOS == "windows" {
+		icons := make([]image.Image, len(icoSizes))
+		for i, size := range icoSizes {
+			icons[i] = ResizeFrame(img, size)
+		}
+		return EncodeICO(icons...)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 将帧文件转换为托盘需要的格式，Windows上的PNG帧会转换为ICO
+func trayIcon(data []byte) ([]byte, error) {
+	if runtime.GOOS != "windows" || !bytes.HasPrefix(data, pngSignature) {
+		return data, nil
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return encodeTrayIcon(img)
+}
+
+// ResizeFrame 将图像等比缩放到size×size的正方形中并居中，缩小时使用区域平均
+func ResizeFrame(src image.Image, size int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	if b.Empty() || size <= 0 {
+		return dst
+	}
+
+	// 等比缩放后的尺寸和偏移
+	scale := float64(max(b.Dx(), b.Dy())) / float64(size)
+	w := max(1, int(float64(b.Dx())/scale+0.5))
+	h := max(1, int(float64(b.Dy())/scale+0.5))
+	offX, offY := (size-w)/2, (size-h)/2
+
+	for y := 0; y < h; y++ {
+		sy0 := b.Min.Y + int(float64(y)*scale)
+		sy1 := max(sy0+1, min(b.Max.Y, b.Min.Y+int(float64(y+1)*scale+0.999)))
+		for x := 0; x < w; x++ {
+			sx0 := b.Min.X + int(float64(x)*scale)
+			sx1 := max(sx0+1, min(b.Max.X, b.Min.X+int(float64(x+1)*scale+0.999)))
+
+			// 预乘alpha后求平均，避免透明像素的颜色渗入
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			c := color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			}
+			dst.Set(offX+x, offY+y, c)
+		}
+	}
+	return dst
+}
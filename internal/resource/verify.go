@@ -0,0 +1,161 @@
+package resource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+)
+
+// MaxFrameSize 帧的最大边长，与ICO格式的上限一致
+const MaxFrameSize = 256
+
+// 精灵图的最大格数，与帧尺寸一起限制解码精灵图时分配的内存
+const maxSpriteCells = 1024
+
+// 帧尺寸是否在1到MaxFrameSize之间
+func validFrameSize(size image.Point) bool {
+	return size.X > 0 && size.Y > 0 && size.X <= MaxFrameSize && size.Y <= MaxFrameSize
+}
+
+// Verify 校验所有已注册的角色，返回发现的所有问题
+// 每一帧都会被解码，并检查文件头、尺寸是否一致以及浅色和深色主题的帧数是否相同
+func (m *Manager) Verify() error {
+	m.mu.RLock()
+	runners := make([]RunnerType, len(m.order))
+	sources := make([]*runnerSource, len(m.order))
+	for i, runner := range m.order {
+		runners[i], sources[i] = runner, m.runners[runner]
+	}
+	m.mu.RUnlock()
+
+	var errs []error
+	for i, runner := range runners {
+		for _, err := range verifySource(sources[i]) {
+			errs = append(errs, fmt.Errorf("runner %s: %w", runner, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 校验角色的所有帧，每个问题以文件路径开头
+func verifySource(source *runnerSource) []error {
+	if len(source.frames) == 0 {
+		return []error{errors.New("no frames")}
+	}
+
+	var errs []error
+	var want image.Point
+	check := func(name string, size image.Point) {
+		switch {
+		case !validFrameSize(size):
+			errs = append(errs, fmt.Errorf("%s: frame size %dx%d must be between 1 and %d", name, size.X, size.Y, MaxFrameSize))
+		case want == image.Point{}:
+			want = size
+		case size != want:
+			errs = append(errs, fmt.Errorf("%s: frame size %dx%d differs from %dx%d", name, size.X, size.Y, want.X, want.Y))
+		}
+	}
+
+	for _, t := range supportThemes {
+		frames, ok := source.frames[t]
+		if !ok {
+			continue
+		}
+		if frames.sprite != "" {
+			size, err := spriteFrameSize(source.fs, frames)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", frames.sprite, err))
+				continue
+			}
+			check(frames.sprite, size)
+			continue
+		}
+		for _, name := range frames.paths {
+			size, err := frameSize(source.fs, name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			check(name, size)
+		}
+	}
+
+	// 只提供一种主题时使用另一个主题的帧，两种都提供时帧数必须相同
+	light, hasLight := source.frames[supportThemes[0]]
+	dark, hasDark := source.frames[supportThemes[1]]
+	if hasLight && hasDark && light.count() != dark.count() {
+		errs = append(errs, fmt.Errorf("%s has %d frames but %s has %d",
+			supportThemes[0], light.count(), supportThemes[1], dark.count()))
+	}
+	return errs
+}
+
+// 读取并解码一帧，返回其尺寸
+// 文件头声明的尺寸超出范围时不解码，由调用方报告尺寸错误，避免按声明的尺寸分配内存
+func frameSize(fsys fs.FS, name string) (image.Point, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return image.Point{}, err
+	}
+	switch {
+	case len(data) == 0:
+		return image.Point{}, errors.New("empty file")
+	case bytes.HasPrefix(data, pngSignature):
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return image.Point{}, err
+		}
+		if size := image.Pt(config.Width, config.Height); !validFrameSize(size) {
+			return size, nil
+		}
+	case isICO(data):
+		if _, err := readICOEntries(data); err != nil {
+			return image.Point{}, err
+		}
+	default:
+		return image.Point{}, errors.New("not a PNG or ICO file")
+	}
+
+	img, err := DecodeIcon(data)
+	if err != nil {
+		return image.Point{}, err
+	}
+	return img.Bounds().Size(), nil
+}
+
+// 解码精灵图，返回每帧的尺寸
+func spriteFrameSize(fsys fs.FS, frames themeFrames) (image.Point, error) {
+	data, err := fs.ReadFile(fsys, frames.sprite)
+	if err != nil {
+		return image.Point{}, err
+	}
+	if len(data) == 0 {
+		return image.Point{}, errors.New("empty file")
+	}
+	layout := frames.layout
+	if layout.Columns*layout.Rows > maxSpriteCells {
+		return image.Point{}, fmt.Errorf("sprite has %d cells, more than %d", layout.Columns*layout.Rows, maxSpriteCells)
+	}
+
+	// 每帧尺寸超出范围时不解码
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Point{}, err
+	}
+	size := image.Pt(config.Width/layout.Columns, config.Height/layout.Rows)
+	if !validFrameSize(size) {
+		return size, nil
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		return image.Point{}, err
+	}
+	return size, nil
+}
+
+// 检查ICO文件头：保留字段为0，类型为1
+func isICO(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0, 0, 1, 0})
+}
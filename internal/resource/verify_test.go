@@ -0,0 +1,60 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerify(t *testing.T) {
+	frame := &fstest.MapFile{Data: pngFrame(16)}
+	ico, err := EncodeICO(solid(16, 16, red))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assets := fstest.MapFS{
+		"assets/cat/light/light_cat_0.ico": {Data: ico},
+		"assets/cat/light/light_cat_1.ico": {Data: ico},
+		"assets/cat/dark/dark_cat_0.ico":   {Data: ico},
+		"assets/cat/dark/dark_cat_1.ico":   {Data: ico},
+		"assets/parrot/light/frame_0.ico":  file(""),
+		"assets/parrot/light/frame_1.ico":  file("GIF89a"),
+		"assets/parrot/light/frame_2.png":  {Data: pngFrame(16)[:20]},
+		"assets/parrot/dark/frame_0.png":   {Data: pngFrame(32)},
+	}
+	m := NewResourceManager(assets)
+
+	err = m.Verify()
+	if err == nil {
+		t.Fatal("Verify succeeded")
+	}
+	for _, want := range []string{
+		"runner parrot: assets/parrot/light/frame_0.ico: empty file",
+		"runner parrot: assets/parrot/light/frame_1.ico: not a PNG or ICO file",
+		"runner parrot: assets/parrot/light/frame_2.png:",
+		"runner parrot: light has 3 frames but dark has 1",
+		"runner horse: no frames",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "runner cat") {
+		t.Errorf("valid runner reported: %v", err)
+	}
+
+	// 尺寸不一致
+	user := fstest.MapFS{
+		"mixed/light/frame_0.png": frame,
+		"mixed/light/frame_1.png": {Data: pngFrame(24)},
+		"mixed/dark/frame_0.png":  frame,
+		"mixed/dark/frame_1.png":  frame,
+	}
+	if err := m.LoadRunners(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(); err == nil || !strings.Contains(err.Error(), "runner mixed: mixed/light/frame_1.png: frame size 24x24 differs from 16x16") {
+		t.Errorf("mixed sizes not reported: %v", err)
+	}
+}
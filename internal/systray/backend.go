@@ -0,0 +1,43 @@
+package systray
+
+// Backend 系统托盘的底层实现
+type Backend interface {
+	// Run 运行系统托盘，阻塞直到Quit被调用
+	Run(onReady, onExit func())
+	// Quit 退出系统托盘
+	Quit()
+	// SetIcon 设置托盘图标
+	SetIcon(icon []byte)
+	// SetTooltip 设置托盘提示文本
+	SetTooltip(tooltip string)
+	// AddMenuItem 添加顶层菜单项
+	AddMenuItem(title, tooltip string) MenuItem
+	// AddMenuItemCheckbox 添加带复选框的顶层菜单项
+	AddMenuItemCheckbox(title, tooltip string, checked bool) MenuItem
+	// AddSeparator 添加分隔线
+	AddSeparator()
+}
+
+// MenuItem 托盘菜单项
+type MenuItem interface {
+	// AddSubMenuItem 添加子菜单项
+	AddSubMenuItem(title, tooltip string) MenuItem
+	// AddSubMenuItemCheckbox 添加带复选框的子菜单项
+	AddSubMenuItemCheckbox(title, tooltip string, checked bool) MenuItem
+	// Clicked 返回菜单项被点击时的通知通道
+	Clicked() <-chan struct{}
+	// Check 勾选菜单项
+	Check()
+	// Uncheck 取消勾选菜单项
+	Uncheck()
+	// SetTitle 设置菜单项标题
+	SetTitle(title string)
+	// Enable 启用菜单项
+	Enable()
+	// Disable 禁用菜单项
+	Disable()
+	// Show 显示菜单项
+	Show()
+	// Hide 隐藏菜单项
+	Hide()
+}
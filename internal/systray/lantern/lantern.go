@@ -0,0 +1,70 @@
+// Package lantern 基于getlantern/systray实现系统托盘后端
+package lantern
+
+import (
+	"github.com/eatmoreapple/go-runcat/internal/systray"
+	getlantern "github.com/getlantern/systray"
+)
+
+// Backend 使用getlantern/systray的系统托盘后端
+type Backend struct{}
+
+// NewBackend 创建一个新的系统托盘后端
+func NewBackend() *Backend {
+	return &Backend{}
+}
+
+// Run 运行系统托盘，阻塞直到Quit被调用
+func (*Backend) Run(onReady, onExit func()) {
+	getlantern.Run(onReady, onExit)
+}
+
+// Quit 退出系统托盘
+func (*Backend) Quit() {
+	getlantern.Quit()
+}
+
+// SetIcon 设置托盘图标
+func (*Backend) SetIcon(icon []byte) {
+	getlantern.SetIcon(icon)
+}
+
+// SetTooltip 设置托盘提示文本
+func (*Backend) SetTooltip(tooltip string) {
+	getlantern.SetTooltip(tooltip)
+}
+
+// AddMenuItem 添加顶层菜单项
+func (*Backend) AddMenuItem(title, tooltip string) systray.MenuItem {
+	return &menuItem{getlantern.AddMenuItem(title, tooltip)}
+}
+
+// AddMenuItemCheckbox 添加带复选框的顶层菜单项
+func (*Backend) AddMenuItemCheckbox(title, tooltip string, checked bool) systray.MenuItem {
+	return &menuItem{getlantern.AddMenuItemCheckbox(title, tooltip, checked)}
+}
+
+// AddSeparator 添加分隔线
+func (*Backend) AddSeparator() {
+	getlantern.AddSeparator()
+}
+
+// menuItem 包装getlantern的菜单项
+type menuItem struct {
+	*getlantern.MenuItem
+}
+
+// AddSubMenuItem 添加子菜单项
+func (i *menuItem) AddSubMenuItem(title, tooltip string) systray.MenuItem {
+	return &menuItem{i.MenuItem.AddSubMenuItem(title, tooltip)}
+}
+
+// AddSubMenuItemCheckbox 添加带复选框的子菜单项
+func (i *menuItem) AddSubMenuItemCheckbox(title, tooltip string, checked bool) systray.MenuItem {
+	return &menuItem{i.MenuItem.AddSubMenuItemCheckbox(title, tooltip, checked)}
+}
+
+// Clicked 返回菜单项被点击时的通知通道
+func (i *menuItem) Clicked() <-chan struct{} {
+	return i.ClickedCh
+}
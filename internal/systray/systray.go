@@ -1,14 +1,20 @@
 package systray
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/eatmoreapple/go-runcat/internal/animation"
+	"github.com/eatmoreapple/go-runcat/internal/monitor"
+	"github.com/eatmoreapple/go-runcat/internal/overlay"
 	"github.com/eatmoreapple/go-runcat/internal/platform"
 	"github.com/eatmoreapple/go-runcat/internal/resource"
 	"github.com/eatmoreapple/go-runcat/internal/theme"
-	"github.com/getlantern/systray"
 )
 
 // SpeedLimitType 表示速度限制类型
@@ -27,8 +33,63 @@ const (
 	SpeedCPU40 SpeedLimitType = "cpu40"
 )
 
+// SpeedLimits 所有速度限制，按菜单顺序排列
+var SpeedLimits = []SpeedLimitType{SpeedDefault, SpeedCPU10, SpeedCPU20, SpeedCPU30, SpeedCPU40}
+
+// FixedUsage 返回速度限制对应的固定指标值，SpeedDefault（跟随指标）返回false
+func (s SpeedLimitType) FixedUsage() (float64, bool) {
+	switch s {
+	case SpeedCPU10:
+		return 10, true
+	case SpeedCPU20:
+		return 20, true
+	case SpeedCPU30:
+		return 30, true
+	case SpeedCPU40:
+		return 40, true
+	default:
+		return 0, false
+	}
+}
+
+// DefaultInterval 默认监控采样间隔
+const DefaultInterval = 3 * time.Second
+
+// Intervals 菜单中可选的采样间隔
+var Intervals = []time.Duration{
+	time.Second,
+	2 * time.Second,
+	3 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Settings 系统托盘的用户设置
+type Settings struct {
+	// 当前选择的角色
+	Runner resource.RunnerType
+	// 当前主题设置
+	Theme theme.Type
+	// 当前速度限制
+	SpeedLimit SpeedLimitType
+	// 驱动动画的监控指标
+	Metric string
+	// 组合多个指标的驱动表达式，非空时优先于Metric
+	DriveExpression string
+	// 监控采样间隔
+	Interval time.Duration
+	// 在图标上叠加指标的方式
+	Overlay overlay.Mode
+	// 当前使用的配置方案，为空表示没有
+	Profile string
+	// 可选的配置方案，按菜单顺序排列
+	Profiles []string
+}
+
 // Manager 系统托盘管理器
 type Manager struct {
+	// 系统托盘后端
+	backend Backend
 	// 平台实现
 	platform platform.Platform
 	// 资源管理器
@@ -36,59 +97,182 @@ type Manager struct {
 	// 主题管理器
 	themeManager *theme.Manager
 
+	// 保护以下状态的互斥锁，菜单操作和回调不在持有锁时调用
+	mu sync.Mutex
 	// 当前选择的角色
 	currentRunner resource.RunnerType
 	// 当前速度限制
 	speedLimit SpeedLimitType
-	// 当前CPU使用率
+	// 当前监控指标
+	metric string
+	// 当前驱动表达式
+	driveExpression string
+	// 当前采样间隔
+	monitorInterval time.Duration
+	// 当前叠加方式
+	overlayMode overlay.Mode
+	// 当前使用的配置方案
+	profile string
+	// 可选的配置方案
+	profiles []string
+	// 当前指标的归一化值 (0-100)
 	cpuUsage float64
-	// 当前图标索引
-	currentIconIndex int
-	// 图标切换间隔
-	animationInterval time.Duration
 	// 最小动画间隔
 	minInterval float64
+	// 设置变化时的回调函数
+	onSettingsChanged func(settings Settings)
+	// 选择配置方案时的回调函数
+	onProfileSelected func(name string)
+	// 菜单创建完成后的回调函数
+	onTrayReady func()
 
 	// 菜单项
-	runnerMenu      map[resource.RunnerType]*systray.MenuItem
-	themeMenu       map[theme.Type]*systray.MenuItem
-	startupMenu     *systray.MenuItem
-	speedLimitMenu  map[SpeedLimitType]*systray.MenuItem
-	taskManagerMenu *systray.MenuItem
+	runnerMenu      map[resource.RunnerType]MenuItem
+	themeMenu       map[theme.Type]MenuItem
+	startupMenu     MenuItem
+	speedLimitMenu  map[SpeedLimitType]MenuItem
+	metricMenu      map[string]MenuItem
+	metricParent    MenuItem
+	expressionMenu  MenuItem
+	intervalMenu    map[time.Duration]MenuItem
+	overlayMenu     map[overlay.Mode]MenuItem
+	profileParent   MenuItem
+	profileMenu     map[string]MenuItem
+	coresMenu       MenuItem
+	coreItems       []MenuItem
+	taskManagerMenu MenuItem
+
+	// 在帧上叠加指标，输出到系统托盘图标
+	overlay *overlay.Sink
+	// 动画引擎，输出到overlay
+	engine *animation.Engine
+}
 
-	// 停止动画的通道
-	stopAnimationCh chan struct{}
-	// 是否正在运行动画
-	animationRunning bool
+// traySink 将动画帧设置为系统托盘图标
+type traySink struct {
+	backend Backend
+}
 
-	// 当前图标数据
-	currentIcons [][]byte
+// ShowFrame 设置系统托盘图标
+func (s traySink) ShowFrame(frame []byte) {
+	s.backend.SetIcon(frame)
 }
 
 // NewSystrayManager 创建一个新的系统托盘管理器
 func NewSystrayManager(
+	b Backend,
 	p platform.Platform,
 	rm *resource.Manager,
 	tm *theme.Manager,
+	settings Settings,
 ) *Manager {
-	return &Manager{
-		platform:          p,
-		resourceManager:   rm,
-		themeManager:      tm,
-		currentRunner:     resource.RunnerCat,
-		speedLimit:        SpeedDefault,
-		animationInterval: 200 * time.Millisecond,
-		minInterval:       25.0,
-		runnerMenu:        make(map[resource.RunnerType]*systray.MenuItem),
-		themeMenu:         make(map[theme.Type]*systray.MenuItem),
-		speedLimitMenu:    make(map[SpeedLimitType]*systray.MenuItem),
-		stopAnimationCh:   make(chan struct{}),
+	if settings.Runner == "" || !rm.HasRunner(settings.Runner) {
+		settings.Runner = resource.RunnerCat
+	}
+	if settings.SpeedLimit == "" {
+		settings.SpeedLimit = SpeedDefault
+	}
+	if settings.Metric == "" {
+		settings.Metric = monitor.SourceCPU
+	}
+	if settings.Interval <= 0 {
+		settings.Interval = DefaultInterval
+	}
+	if settings.Overlay == "" {
+		settings.Overlay = overlay.ModeOff
+	}
+	overlaySink := overlay.NewSink(traySink{backend: b})
+	overlaySink.SetMode(settings.Overlay)
+	m := &Manager{
+		backend:         b,
+		platform:        p,
+		resourceManager: rm,
+		themeManager:    tm,
+		currentRunner:   settings.Runner,
+		speedLimit:      settings.SpeedLimit,
+		metric:          settings.Metric,
+		driveExpression: settings.DriveExpression,
+		monitorInterval: settings.Interval,
+		overlayMode:     settings.Overlay,
+		profile:         settings.Profile,
+		profiles:        slices.Clone(settings.Profiles),
+		overlay:         overlaySink,
+		engine:          animation.NewEngine(overlaySink),
+		minInterval:     25.0,
+		runnerMenu:      make(map[resource.RunnerType]MenuItem),
+		themeMenu:       make(map[theme.Type]MenuItem),
+		speedLimitMenu:  make(map[SpeedLimitType]MenuItem),
+		metricMenu:      make(map[string]MenuItem),
+		intervalMenu:    make(map[time.Duration]MenuItem),
+		overlayMenu:     make(map[overlay.Mode]MenuItem),
+		profileMenu:     make(map[string]MenuItem),
+	}
+	m.applySpeedLimit()
+	return m
+}
+
+// SetOnSettingsChanged 设置用户通过菜单修改设置时的回调函数
+func (m *Manager) SetOnSettingsChanged(callback func(settings Settings)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onSettingsChanged = callback
+}
+
+// SetOnProfileSelected 设置用户通过菜单选择配置方案时的回调函数
+// 回调负责加载方案中的设置，并通过ApplySettings应用到系统托盘
+func (m *Manager) SetOnProfileSelected(callback func(name string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onProfileSelected = callback
+}
+
+// SetOnReady 设置系统托盘就绪、菜单创建完成后的回调函数
+// 在此之后调用ApplySettings才会同步菜单的选中状态
+func (m *Manager) SetOnReady(callback func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onTrayReady = callback
+}
+
+// GetSettings 获取当前设置
+func (m *Manager) GetSettings() Settings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Settings{
+		Runner:          m.currentRunner,
+		Theme:           m.themeManager.GetTheme(),
+		SpeedLimit:      m.speedLimit,
+		Metric:          m.metric,
+		DriveExpression: m.driveExpression,
+		Interval:        m.monitorInterval,
+		Overlay:         m.overlayMode,
+		Profile:         m.profile,
+		Profiles:        slices.Clone(m.profiles),
+	}
+}
+
+// AnimationInterval 返回当前的动画帧间隔
+func (m *Manager) AnimationInterval() time.Duration {
+	return m.engine.Interval()
+}
+
+// 通知设置变化
+func (m *Manager) notifySettingsChanged() {
+	// 通过菜单修改单个设置后不再属于任何配置方案
+	m.clearProfile()
+
+	m.mu.Lock()
+	callback := m.onSettingsChanged
+	m.mu.Unlock()
+
+	if callback != nil {
+		callback(m.GetSettings())
 	}
 }
 
 // Start 启动系统托盘
 func (m *Manager) Start() {
-	systray.Run(m.onReady, m.onExit)
+	m.backend.Run(m.onReady, m.onExit)
 }
 
 // onReady 系统托盘准备就绪时的回调
@@ -100,12 +284,20 @@ func (m *Manager) onReady() {
 	m.createMenuItems()
 
 	// 启动动画
-	m.startAnimation()
+	m.startAnimation(context.Background())
 
 	// 设置主题变化回调
 	m.themeManager.SetOnThemeChanged(func(t theme.Type) {
 		m.updateIcon()
 	})
+
+	m.mu.Lock()
+	callback := m.onTrayReady
+	m.mu.Unlock()
+
+	if callback != nil {
+		callback()
+	}
 }
 
 // onExit 系统托盘退出时的回调
@@ -113,35 +305,57 @@ func (m *Manager) onExit() {
 	m.stopAnimation()
 }
 
-// SetCPUUsage 设置CPU使用率
-func (m *Manager) SetCPUUsage(usage float64) {
-	m.cpuUsage = usage
+// Quit 停止动画并退出系统托盘，使Start返回
+func (m *Manager) Quit() {
+	m.stopAnimation()
+	m.backend.Quit()
+}
+
+// SetUsage 设置驱动动画的监控指标采样
+func (m *Manager) SetUsage(sample monitor.Sample) {
+	// 根据CPU使用率调整动画速度
+	m.mu.Lock()
+	m.cpuUsage = sample.Value
+	m.applySpeedLimit()
+	m.mu.Unlock()
+
+	// 更新叠加在图标上的数值
+	m.overlay.SetValue(sample.Value)
 
 	// 更新系统托盘提示文本
-	systray.SetTooltip(fmt.Sprintf("CPU: %.1f%%", usage))
+	m.backend.SetTooltip(sample.String())
 
-	// 根据CPU使用率调整动画速度
-	if m.speedLimit == SpeedDefault {
-		// 根据CPU使用率计算动画间隔
-		// 使用与原始RunCat相同的算法
-		interval := 200.0 / float64(max(1.0, min(20.0, usage/5.0)))
-		m.animationInterval = time.Duration(interval) * time.Millisecond
-	}
+	// 更新每核心使用率
+	m.updateCores(sample.CoreUsages())
 }
 
 // 创建菜单项
 func (m *Manager) createMenuItems() {
+	settings := m.GetSettings()
+
+	// Profiles菜单，没有配置方案时隐藏
+	profileMenuItem := m.backend.AddMenuItem("Profiles", "Switch between settings profiles")
+	m.mu.Lock()
+	m.profileParent = profileMenuItem
+	m.mu.Unlock()
+	m.updateProfileMenu()
+
 	// Runner菜单
-	runnerMenuItem := systray.AddMenuItem("Runner", "Select runner")
-	m.runnerMenu[resource.RunnerCat] = runnerMenuItem.AddSubMenuItemCheckbox("Cat", "Cat runner", m.currentRunner == resource.RunnerCat)
-	m.runnerMenu[resource.RunnerParrot] = runnerMenuItem.AddSubMenuItemCheckbox("Parrot", "Parrot runner", m.currentRunner == resource.RunnerParrot)
-	m.runnerMenu[resource.RunnerHorse] = runnerMenuItem.AddSubMenuItemCheckbox("Horse", "Horse runner", m.currentRunner == resource.RunnerHorse)
+	runnerMenuItem := m.backend.AddMenuItem("Runner", "Select runner")
+	for _, runner := range m.resourceManager.Runners() {
+		info := m.resourceManager.RunnerInfo(runner)
+		tooltip := fmt.Sprintf("%s runner", info.Label)
+		if info.Author != "" {
+			tooltip = fmt.Sprintf("%s by %s", info.Label, info.Author)
+		}
+		m.runnerMenu[runner] = runnerMenuItem.AddSubMenuItemCheckbox(info.Label, tooltip, settings.Runner == runner)
+	}
 
 	// Theme菜单
-	themeMenuItem := systray.AddMenuItem("Theme", "Select theme")
-	m.themeMenu[theme.AutoType] = themeMenuItem.AddSubMenuItemCheckbox("Auto", "Auto theme", m.themeManager.GetTheme() == theme.AutoType)
-	m.themeMenu[theme.LightType] = themeMenuItem.AddSubMenuItemCheckbox("Light", "Light theme", m.themeManager.GetTheme() == theme.LightType)
-	m.themeMenu[theme.DarkType] = themeMenuItem.AddSubMenuItemCheckbox("Dark", "Dark theme", m.themeManager.GetTheme() == theme.DarkType)
+	themeMenuItem := m.backend.AddMenuItem("Theme", "Select theme")
+	m.themeMenu[theme.AutoType] = themeMenuItem.AddSubMenuItemCheckbox("Auto", "Auto theme", settings.Theme == theme.AutoType)
+	m.themeMenu[theme.LightType] = themeMenuItem.AddSubMenuItemCheckbox("Light", "Light theme", settings.Theme == theme.LightType)
+	m.themeMenu[theme.DarkType] = themeMenuItem.AddSubMenuItemCheckbox("Dark", "Dark theme", settings.Theme == theme.DarkType)
 
 	// Startup菜单
 	startupEnabled, err := m.platform.IsStartupEnabled()
@@ -149,38 +363,69 @@ func (m *Manager) createMenuItems() {
 		log.Printf("Failed to check startup status: %v", err)
 		startupEnabled = false
 	}
-	m.startupMenu = systray.AddMenuItemCheckbox("Start at Login", "Start at login", startupEnabled)
+	m.startupMenu = m.backend.AddMenuItemCheckbox("Start at Login", "Start at login", startupEnabled)
 
 	// Speed Limit菜单
-	speedLimitMenuItem := systray.AddMenuItem("Runner Speed Limit", "Set runner speed limit")
-	m.speedLimitMenu[SpeedDefault] = speedLimitMenuItem.AddSubMenuItemCheckbox("Default", "Default speed", m.speedLimit == SpeedDefault)
-	m.speedLimitMenu[SpeedCPU10] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 10%", "Limit to CPU 10%", m.speedLimit == SpeedCPU10)
-	m.speedLimitMenu[SpeedCPU20] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 20%", "Limit to CPU 20%", m.speedLimit == SpeedCPU20)
-	m.speedLimitMenu[SpeedCPU30] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 30%", "Limit to CPU 30%", m.speedLimit == SpeedCPU30)
-	m.speedLimitMenu[SpeedCPU40] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 40%", "Limit to CPU 40%", m.speedLimit == SpeedCPU40)
+	speedLimitMenuItem := m.backend.AddMenuItem("Runner Speed Limit", "Set runner speed limit")
+	m.speedLimitMenu[SpeedDefault] = speedLimitMenuItem.AddSubMenuItemCheckbox("Default", "Default speed", settings.SpeedLimit == SpeedDefault)
+	m.speedLimitMenu[SpeedCPU10] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 10%", "Limit to CPU 10%", settings.SpeedLimit == SpeedCPU10)
+	m.speedLimitMenu[SpeedCPU20] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 20%", "Limit to CPU 20%", settings.SpeedLimit == SpeedCPU20)
+	m.speedLimitMenu[SpeedCPU30] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 30%", "Limit to CPU 30%", settings.SpeedLimit == SpeedCPU30)
+	m.speedLimitMenu[SpeedCPU40] = speedLimitMenuItem.AddSubMenuItemCheckbox("CPU 40%", "Limit to CPU 40%", settings.SpeedLimit == SpeedCPU40)
+
+	// Metric菜单
+	metricMenuItem := m.backend.AddMenuItem("Metric", "Select the metric that drives the runner")
+	for _, name := range monitor.SourceNames() {
+		label := monitor.SourceLabel(name)
+		m.metricMenu[name] = metricMenuItem.AddSubMenuItemCheckbox(label, fmt.Sprintf("Run with %s", label), settings.DriveExpression == "" && settings.Metric == name)
+	}
+	m.mu.Lock()
+	m.metricParent = metricMenuItem
+	m.mu.Unlock()
+	// 配置了驱动表达式时显示表达式选项
+	m.updateExpressionMenu(settings.DriveExpression)
+
+	// Update Interval菜单
+	intervalMenuItem := m.backend.AddMenuItem("Update Interval", "Set how often the metric is sampled")
+	for _, interval := range Intervals {
+		m.intervalMenu[interval] = intervalMenuItem.AddSubMenuItemCheckbox(interval.String(), fmt.Sprintf("Sample every %s", interval), settings.Interval == interval)
+	}
+
+	// Overlay菜单
+	overlayMenuItem := m.backend.AddMenuItem("Overlay", "Show the metric on the icon")
+	for _, mode := range overlay.Modes {
+		m.overlayMenu[mode] = overlayMenuItem.AddSubMenuItemCheckbox(mode.Label(), fmt.Sprintf("%s overlay", mode.Label()), settings.Overlay == mode)
+	}
+
+	// CPU Cores菜单，采样到每核心数据后填充
+	coresMenu := m.backend.AddMenuItem("CPU Cores", "Per-core CPU usage")
+	coresMenu.Disable()
+	m.mu.Lock()
+	m.coresMenu = coresMenu
+	m.mu.Unlock()
 
 	// 分隔线
-	systray.AddSeparator()
+	m.backend.AddSeparator()
 
 	// 版本信息
-	m.taskManagerMenu = systray.AddMenuItem("Task Manger", "")
+	m.taskManagerMenu = m.backend.AddMenuItem("Task Manger", "")
 
 	// Author
-	systray.AddMenuItem("Author", "eatmoreapple")
+	m.backend.AddMenuItem("Author", "eatmoreapple")
 
 	// 退出菜单
-	quitItem := systray.AddMenuItem("Quit", "Quit the application")
+	quitItem := m.backend.AddMenuItem("Quit", "Quit the application")
 
 	// 处理菜单事件
 	go m.handleMenuEvents(quitItem)
 }
 
 // 处理菜单事件
-func (m *Manager) handleMenuEvents(quitItem *systray.MenuItem) {
+func (m *Manager) handleMenuEvents(quitItem MenuItem) {
 	// Runner菜单事件
 	for runner, item := range m.runnerMenu {
-		go func(r resource.RunnerType, i *systray.MenuItem) {
-			for range i.ClickedCh {
+		go func(r resource.RunnerType, i MenuItem) {
+			for range i.Clicked() {
 				m.setRunner(r)
 			}
 		}(runner, item)
@@ -188,8 +433,8 @@ func (m *Manager) handleMenuEvents(quitItem *systray.MenuItem) {
 
 	// Theme菜单事件
 	for t, item := range m.themeMenu {
-		go func(themeType theme.Type, i *systray.MenuItem) {
-			for range i.ClickedCh {
+		go func(themeType theme.Type, i MenuItem) {
+			for range i.Clicked() {
 				m.setTheme(themeType)
 			}
 		}(t, item)
@@ -197,23 +442,50 @@ func (m *Manager) handleMenuEvents(quitItem *systray.MenuItem) {
 
 	// Startup菜单事件
 	go func() {
-		for range m.startupMenu.ClickedCh {
+		for range m.startupMenu.Clicked() {
 			m.toggleStartup()
 		}
 	}()
 
 	// Speed Limit菜单事件
 	for speed, item := range m.speedLimitMenu {
-		go func(s SpeedLimitType, i *systray.MenuItem) {
-			for range i.ClickedCh {
+		go func(s SpeedLimitType, i MenuItem) {
+			for range i.Clicked() {
 				m.setSpeedLimit(s)
 			}
 		}(speed, item)
 	}
 
+	// Metric菜单事件
+	for name, item := range m.metricMenu {
+		go func(n string, i MenuItem) {
+			for range i.Clicked() {
+				m.setMetric(n)
+			}
+		}(name, item)
+	}
+
+	// Update Interval菜单事件
+	for interval, item := range m.intervalMenu {
+		go func(d time.Duration, i MenuItem) {
+			for range i.Clicked() {
+				m.setInterval(d)
+			}
+		}(interval, item)
+	}
+
+	// Overlay菜单事件
+	for mode, item := range m.overlayMenu {
+		go func(o overlay.Mode, i MenuItem) {
+			for range i.Clicked() {
+				m.setOverlay(o)
+			}
+		}(mode, item)
+	}
+
 	// Task Manager菜单事件
 	go func() {
-		for range m.taskManagerMenu.ClickedCh {
+		for range m.taskManagerMenu.Clicked() {
 			if err := m.platform.OpenTaskManager(); err != nil {
 				log.Printf("Failed to open task manager: %v", err)
 			}
@@ -222,16 +494,58 @@ func (m *Manager) handleMenuEvents(quitItem *systray.MenuItem) {
 
 	// 退出事件
 	go func() {
-		<-quitItem.ClickedCh
-		systray.Quit()
+		<-quitItem.Clicked()
+		m.backend.Quit()
 	}()
 }
 
+// ApplySettings 应用外部修改的设置（例如重新加载的配置文件），同步菜单的选中状态
+// 与菜单操作不同，不会触发设置变化的回调；配置方案总是被替换
+func (m *Manager) ApplySettings(settings Settings) {
+	m.applyProfile(settings.Profile, settings.Profiles)
+	if settings.Runner != "" {
+		if m.resourceManager.HasRunner(settings.Runner) {
+			m.applyRunner(settings.Runner)
+		} else {
+			log.Printf("Ignoring unavailable runner %s", settings.Runner)
+		}
+	}
+	if settings.Theme != "" {
+		m.applyTheme(settings.Theme)
+	}
+	if settings.SpeedLimit != "" {
+		m.applySpeedLimitSetting(settings.SpeedLimit)
+	}
+	if settings.Metric != "" || settings.DriveExpression != "" {
+		m.applyMetric(settings.Metric, settings.DriveExpression)
+	}
+	if settings.Interval > 0 {
+		m.applyInterval(settings.Interval)
+	}
+	if settings.Overlay != "" {
+		m.applyOverlay(settings.Overlay)
+	}
+}
+
 // 设置角色
 func (m *Manager) setRunner(runner resource.RunnerType) {
+	if m.applyRunner(runner) {
+		m.notifySettingsChanged()
+	}
+}
+
+// 切换角色并更新菜单，返回设置是否变化
+func (m *Manager) applyRunner(runner resource.RunnerType) bool {
+	m.mu.Lock()
 	if m.currentRunner == runner {
-		return
+		m.mu.Unlock()
+		return false
 	}
+	m.currentRunner = runner
+	m.mu.Unlock()
+
+	// 新角色从第一帧开始
+	m.engine.Rewind()
 
 	// 更新选中状态
 	for r, item := range m.runnerMenu {
@@ -242,13 +556,19 @@ func (m *Manager) setRunner(runner resource.RunnerType) {
 		}
 	}
 
-	m.currentRunner = runner
-	m.currentIconIndex = 0
 	m.updateIcon()
+	return true
 }
 
 // 设置主题
 func (m *Manager) setTheme(t theme.Type) {
+	if m.applyTheme(t) {
+		m.notifySettingsChanged()
+	}
+}
+
+// 切换主题并更新菜单，返回设置是否变化
+func (m *Manager) applyTheme(t theme.Type) bool {
 	// 更新选中状态
 	for themeType, item := range m.themeMenu {
 		if themeType == t {
@@ -258,7 +578,11 @@ func (m *Manager) setTheme(t theme.Type) {
 		}
 	}
 
+	if m.themeManager.GetTheme() == t {
+		return false
+	}
 	m.themeManager.SetTheme(t)
+	return true
 }
 
 // 切换开机自启动
@@ -278,9 +602,21 @@ func (m *Manager) toggleStartup() {
 
 // 设置速度限制
 func (m *Manager) setSpeedLimit(speed SpeedLimitType) {
+	if m.applySpeedLimitSetting(speed) {
+		m.notifySettingsChanged()
+	}
+}
+
+// 切换速度限制并更新菜单，返回设置是否变化
+func (m *Manager) applySpeedLimitSetting(speed SpeedLimitType) bool {
+	m.mu.Lock()
 	if m.speedLimit == speed {
-		return
+		m.mu.Unlock()
+		return false
 	}
+	m.speedLimit = speed
+	m.applySpeedLimit()
+	m.mu.Unlock()
 
 	// 更新选中状态
 	for s, item := range m.speedLimitMenu {
@@ -290,82 +626,303 @@ func (m *Manager) setSpeedLimit(speed SpeedLimitType) {
 			item.Uncheck()
 		}
 	}
+	return true
+}
 
-	m.speedLimit = speed
+// 设置监控指标
+func (m *Manager) setMetric(metric string) {
+	// 表达式选项只在表达式生效时显示，无需切换
+	if metric == monitor.SourceExpression {
+		return
+	}
+	// 选择单个指标后清除驱动表达式
+	if m.applyMetric(metric, "") {
+		m.notifySettingsChanged()
+	}
+}
 
-	// 根据速度限制设置动画间隔
-	switch speed {
-	case SpeedDefault:
-		// 使用CPU使用率动态调整
-		m.SetCPUUsage(m.cpuUsage)
-	case SpeedCPU10:
-		m.animationInterval = time.Duration(100) * time.Millisecond
-	case SpeedCPU20:
-		m.animationInterval = time.Duration(50) * time.Millisecond
-	case SpeedCPU30:
-		m.animationInterval = time.Duration(33) * time.Millisecond
-	case SpeedCPU40:
-		m.animationInterval = time.Duration(25) * time.Millisecond
+// 切换监控指标或驱动表达式并更新菜单，返回设置是否变化
+func (m *Manager) applyMetric(metric, driveExpression string) bool {
+	m.mu.Lock()
+	if m.metric == metric && m.driveExpression == driveExpression {
+		m.mu.Unlock()
+		return false
 	}
+	m.metric = metric
+	m.driveExpression = driveExpression
+	m.mu.Unlock()
+
+	// 更新选中状态
+	for name, item := range m.metricMenu {
+		if driveExpression == "" && name == metric {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+	m.updateExpressionMenu(driveExpression)
+	return true
 }
 
-// 更新图标
-func (m *Manager) updateIcon() {
-	// 获取当前主题
-	currentTheme := m.themeManager.GetActualTheme()
-	// 加载图标
-	icons, err := m.resourceManager.LoadIcons(m.currentRunner, currentTheme)
-	if err != nil {
-		// 图标加载失败，使用默认图标
-		log.Printf("Failed to load icons: %v", err)
+// 显示或隐藏驱动表达式选项，菜单创建之前不做任何事
+func (m *Manager) updateExpressionMenu(driveExpression string) {
+	m.mu.Lock()
+	parent, item := m.metricParent, m.expressionMenu
+	if parent != nil && item == nil && driveExpression != "" {
+		item = parent.AddSubMenuItemCheckbox("", "Run with drive_expression from config", true)
+		m.expressionMenu = item
+		// 表达式选项只用于显示，点击无效
+		go func() {
+			for range item.Clicked() {
+			}
+		}()
+	}
+	m.mu.Unlock()
+
+	if item == nil {
+		return
+	}
+	if driveExpression == "" {
+		item.Uncheck()
+		item.Hide()
 		return
 	}
+	item.SetTitle(fmt.Sprintf("Expression: %s", driveExpression))
+	item.Check()
+	item.Show()
+}
+
+// 选择配置方案，由回调加载方案中的设置
+func (m *Manager) selectProfile(name string) {
+	m.mu.Lock()
+	callback := m.onProfileSelected
+	m.mu.Unlock()
 
-	// 缓存图标
-	m.currentIcons = icons
+	if callback != nil {
+		callback(name)
+	}
+}
 
-	// 设置当前图标
-	if m.currentIconIndex >= len(icons) {
-		m.currentIconIndex = 0
+// 替换当前配置方案和可选的配置方案并更新菜单，返回设置是否变化
+func (m *Manager) applyProfile(profile string, profiles []string) bool {
+	m.mu.Lock()
+	if m.profile == profile && slices.Equal(m.profiles, profiles) {
+		m.mu.Unlock()
+		return false
 	}
+	m.profile = profile
+	m.profiles = slices.Clone(profiles)
+	m.mu.Unlock()
 
-	// 设置系统托盘图标
-	systray.SetIcon(icons[m.currentIconIndex])
+	m.updateProfileMenu()
+	return true
 }
 
-// 启动动画
-func (m *Manager) startAnimation() {
-	if m.animationRunning {
+// 清除当前配置方案
+func (m *Manager) clearProfile() {
+	m.mu.Lock()
+	if m.profile == "" {
+		m.mu.Unlock()
 		return
 	}
+	m.profile = ""
+	m.mu.Unlock()
 
-	m.animationRunning = true
-	go func() {
-		for {
-			select {
-			case <-m.stopAnimationCh:
-				m.animationRunning = false
-				return
-			case <-time.After(m.animationInterval):
-				// 更新图标索引
-				m.currentIconIndex++
-				iconCount := m.resourceManager.GetIconCount(m.currentRunner, m.themeManager.GetActualTheme())
-				if m.currentIconIndex >= iconCount {
-					m.currentIconIndex = 0
-				}
-
-				// 更新图标
-				m.updateIcon()
+	m.updateProfileMenu()
+}
+
+// 按可选的配置方案创建、显示或隐藏菜单项并更新选中状态，菜单创建之前不做任何事
+// 菜单项无法删除，移除的方案只隐藏
+func (m *Manager) updateProfileMenu() {
+	m.mu.Lock()
+	parent, profile, profiles := m.profileParent, m.profile, m.profiles
+	if parent == nil {
+		m.mu.Unlock()
+		return
+	}
+	for _, name := range profiles {
+		if _, ok := m.profileMenu[name]; ok {
+			continue
+		}
+		item := parent.AddSubMenuItemCheckbox(name, fmt.Sprintf("Switch to the %s profile", name), false)
+		m.profileMenu[name] = item
+		go func() {
+			for range item.Clicked() {
+				m.selectProfile(name)
 			}
+		}()
+	}
+	items := maps.Clone(m.profileMenu)
+	m.mu.Unlock()
+
+	if len(profiles) == 0 {
+		parent.Hide()
+	} else {
+		parent.Show()
+	}
+	for name, item := range items {
+		if !slices.Contains(profiles, name) {
+			item.Uncheck()
+			item.Hide()
+			continue
 		}
-	}()
+		if name == profile {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+		item.Show()
+	}
 }
 
-// 停止动画
-func (m *Manager) stopAnimation() {
-	if !m.animationRunning {
+// 设置采样间隔
+func (m *Manager) setInterval(interval time.Duration) {
+	if m.applyInterval(interval) {
+		m.notifySettingsChanged()
+	}
+}
+
+// 切换采样间隔并更新菜单，返回设置是否变化
+func (m *Manager) applyInterval(interval time.Duration) bool {
+	m.mu.Lock()
+	if m.monitorInterval == interval {
+		m.mu.Unlock()
+		return false
+	}
+	m.monitorInterval = interval
+	m.mu.Unlock()
+
+	// 更新选中状态
+	for d, item := range m.intervalMenu {
+		if d == interval {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+	return true
+}
+
+// 设置叠加方式
+func (m *Manager) setOverlay(mode overlay.Mode) {
+	if m.applyOverlay(mode) {
+		m.notifySettingsChanged()
+	}
+}
+
+// 切换叠加方式并更新菜单，返回设置是否变化
+func (m *Manager) applyOverlay(mode overlay.Mode) bool {
+	m.mu.Lock()
+	if m.overlayMode == mode {
+		m.mu.Unlock()
+		return false
+	}
+	m.overlayMode = mode
+	m.mu.Unlock()
+
+	m.overlay.SetMode(mode)
+
+	// 更新选中状态
+	for o, item := range m.overlayMenu {
+		if o == mode {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+	return true
+}
+
+// 更新每核心使用率子菜单，只在监控回调中调用
+// 监控可能在系统托盘就绪之前启动，菜单尚未创建时忽略
+func (m *Manager) updateCores(cores []float64) {
+	m.mu.Lock()
+	coresMenu := m.coresMenu
+	if coresMenu == nil || len(cores) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	// 按需补充核心菜单项
+	var added []MenuItem
+	for len(m.coreItems) < len(cores) {
+		item := coresMenu.AddSubMenuItem("", "")
+		m.coreItems = append(m.coreItems, item)
+		added = append(added, item)
+	}
+	coreItems := slices.Clone(m.coreItems)
+	m.mu.Unlock()
+
+	coresMenu.Enable()
+	for _, item := range added {
+		item.Disable()
+	}
+
+	// 找出最繁忙的核心
+	hottest := 0
+	for i, usage := range cores {
+		if usage > cores[hottest] {
+			hottest = i
+		}
+	}
+
+	for i, item := range coreItems {
+		if i >= len(cores) {
+			item.Hide()
+			continue
+		}
+		title := fmt.Sprintf("Core %d: %.1f%%", i, cores[i])
+		if i == hottest {
+			title += " (hottest)"
+		}
+		item.SetTitle(title)
+		item.Show()
+	}
+}
+
+// 根据速度限制设置动画速度，调用时需持有锁
+func (m *Manager) applySpeedLimit() {
+	// 限速时使用固定的指标值，否则跟随CPU使用率
+	usage := m.cpuUsage
+	if fixed, ok := m.speedLimit.FixedUsage(); ok {
+		usage = fixed
+	}
+	m.engine.SetUsage(usage)
+}
+
+// 按当前角色和主题加载图标并交给动画引擎
+func (m *Manager) updateIcon() {
+	m.mu.Lock()
+	runner := m.currentRunner
+	m.mu.Unlock()
+
+	// 获取当前主题
+	currentTheme := m.themeManager.GetActualTheme()
+	// 加载图标
+	icons, err := m.resourceManager.LoadIcons(runner, currentTheme)
+	if err != nil {
+		// 图标加载失败，保留当前图标
+		log.Printf("Failed to load icons: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	// 加载期间角色已切换，由切换方负责更新
+	stale := runner != m.currentRunner
+	m.mu.Unlock()
+	if stale {
 		return
 	}
 
-	m.stopAnimationCh <- struct{}{}
+	m.engine.SetTiming(m.resourceManager.RunnerInfo(runner).Timing)
+	m.engine.SetFrames(icons)
+}
+
+// 启动动画，直到ctx取消或调用stopAnimation
+func (m *Manager) startAnimation(ctx context.Context) {
+	m.engine.Start(ctx)
+}
+
+// 停止动画并等待动画循环退出
+func (m *Manager) stopAnimation() {
+	m.engine.Stop()
 }
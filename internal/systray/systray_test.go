@@ -0,0 +1,521 @@
+package systray_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/eatmoreapple/go-runcat/internal/monitor"
+	"github.com/eatmoreapple/go-runcat/internal/overlay"
+	"github.com/eatmoreapple/go-runcat/internal/resource"
+	"github.com/eatmoreapple/go-runcat/internal/systray"
+	"github.com/eatmoreapple/go-runcat/internal/systray/systraytest"
+	"github.com/eatmoreapple/go-runcat/internal/theme"
+)
+
+// fakePlatform 返回固定系统主题的平台实现
+type fakePlatform struct {
+	theme string
+}
+
+func (p fakePlatform) GetSystemTheme() string { return p.theme }
+
+func (fakePlatform) SetStartup(bool) error { return nil }
+
+func (fakePlatform) IsStartupEnabled() (bool, error) { return false, nil }
+
+func (fakePlatform) OpenTaskManager() error { return nil }
+
+// 每个图标的内容为"角色/主题/索引"，便于断言
+func testAssets() fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, runner := range []string{"cat", "parrot"} {
+		for _, t := range []string{"light", "dark"} {
+			for i := 0; i < 3; i++ {
+				path := fmt.Sprintf("assets/%s/%s/%s_%s_%d.ico", runner, t, t, runner, i)
+				fsys[path] = &fstest.MapFile{Data: fmt.Appendf(nil, "%s/%s/%d", runner, t, i)}
+			}
+		}
+	}
+	return fsys
+}
+
+type harness struct {
+	manager  *systray.Manager
+	backend  *systraytest.Backend
+	settings chan systray.Settings
+}
+
+// 启动使用内存后端的托盘管理器，测试结束时退出
+func startManager(t *testing.T, systemTheme string, settings systray.Settings) *harness {
+	t.Helper()
+	return startManagerWith(t, resource.NewResourceManager(testAssets()), systemTheme, settings)
+}
+
+// 使用指定资源管理器启动托盘管理器
+func startManagerWith(t *testing.T, rm *resource.Manager, systemTheme string, settings systray.Settings) *harness {
+	t.Helper()
+
+	p := fakePlatform{theme: systemTheme}
+	tm := theme.NewManager(p)
+	if settings.Theme != "" {
+		tm.SetTheme(settings.Theme)
+	}
+	b := systraytest.NewBackend()
+	m := systray.NewSystrayManager(b, p, rm, tm, settings)
+
+	h := &harness{manager: m, backend: b, settings: make(chan systray.Settings, 16)}
+	m.SetOnSettingsChanged(func(s systray.Settings) { h.settings <- s })
+
+	done := make(chan struct{})
+	go func() {
+		m.Start()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		m.Quit()
+		<-done
+	})
+
+	select {
+	case <-b.Ready():
+	case <-time.After(2 * time.Second):
+		t.Fatal("tray did not become ready")
+	}
+	return h
+}
+
+// 等待条件成立
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 点击菜单项
+func (h *harness) click(t *testing.T, path ...string) {
+	t.Helper()
+	item := h.backend.Item(path...)
+	if item == nil {
+		t.Fatalf("menu item %q not found", path)
+	}
+	item.Click()
+}
+
+// 等待设置变化的通知
+func (h *harness) nextSettings(t *testing.T) systray.Settings {
+	t.Helper()
+	select {
+	case s := <-h.settings:
+		return s
+	case <-time.After(2 * time.Second):
+		t.Fatal("settings change was not reported")
+		return systray.Settings{}
+	}
+}
+
+// 当前图标是否属于指定角色和主题
+func (h *harness) showing(prefix string) func() bool {
+	return func() bool {
+		return bytes.HasPrefix(h.backend.Icon(), []byte(prefix))
+	}
+}
+
+func (h *harness) checked(t *testing.T, menu string) []string {
+	t.Helper()
+	item := h.backend.Item(menu)
+	if item == nil {
+		t.Fatalf("menu %q not found", menu)
+	}
+	return item.CheckedChildren()
+}
+
+func TestRunnerSwitching(t *testing.T) {
+	h := startManager(t, "light", systray.Settings{})
+
+	eventually(t, "cat icon", h.showing("cat/light/"))
+	if got := h.checked(t, "Runner"); !slices.Equal(got, []string{"Cat"}) {
+		t.Fatalf("checked runners %v, want [Cat]", got)
+	}
+
+	h.click(t, "Runner", "Parrot")
+	if s := h.nextSettings(t); s.Runner != resource.RunnerParrot {
+		t.Fatalf("reported runner %q, want %q", s.Runner, resource.RunnerParrot)
+	}
+	eventually(t, "parrot icon", h.showing("parrot/light/"))
+	if got := h.checked(t, "Runner"); !slices.Equal(got, []string{"Parrot"}) {
+		t.Fatalf("checked runners %v, want [Parrot]", got)
+	}
+
+	// 新角色从第一帧开始
+	for _, icon := range h.backend.Icons() {
+		if bytes.HasPrefix(icon, []byte("parrot/")) {
+			if string(icon) != "parrot/light/0" {
+				t.Fatalf("first parrot frame %q, want %q", icon, "parrot/light/0")
+			}
+			break
+		}
+	}
+
+	// 再次选择当前角色不会产生设置变化
+	h.click(t, "Runner", "Parrot")
+	select {
+	case s := <-h.settings:
+		t.Fatalf("unexpected settings change %+v", s)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestThemeSwitching(t *testing.T) {
+	h := startManager(t, "dark", systray.Settings{})
+
+	// 自动主题跟随系统
+	eventually(t, "dark icon", h.showing("cat/dark/"))
+	if got := h.checked(t, "Theme"); !slices.Equal(got, []string{"Auto"}) {
+		t.Fatalf("checked themes %v, want [Auto]", got)
+	}
+
+	h.click(t, "Theme", "Light")
+	if s := h.nextSettings(t); s.Theme != theme.LightType {
+		t.Fatalf("reported theme %q, want %q", s.Theme, theme.LightType)
+	}
+	eventually(t, "light icon", h.showing("cat/light/"))
+	if got := h.checked(t, "Theme"); !slices.Equal(got, []string{"Light"}) {
+		t.Fatalf("checked themes %v, want [Light]", got)
+	}
+
+	// 主题和角色的切换互不影响
+	h.click(t, "Runner", "Parrot")
+	h.nextSettings(t)
+	eventually(t, "light parrot icon", h.showing("parrot/light/"))
+
+	h.click(t, "Theme", "Auto")
+	if s := h.nextSettings(t); s.Theme != theme.AutoType {
+		t.Fatalf("reported theme %q, want %q", s.Theme, theme.AutoType)
+	}
+	eventually(t, "dark parrot icon", h.showing("parrot/dark/"))
+}
+
+func TestSpeedLimits(t *testing.T) {
+	h := startManager(t, "light", systray.Settings{})
+
+	sample := monitor.Sample{Source: monitor.SourceCPU, Unit: "%", Value: 100, Raw: 100}
+	h.manager.SetUsage(sample)
+	if got := h.manager.AnimationInterval(); got != 10*time.Millisecond {
+		t.Fatalf("interval at 100%% usage %v, want 10ms", got)
+	}
+	if got, want := h.backend.Tooltip(), sample.String(); got != want {
+		t.Fatalf("tooltip %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		item  string
+		limit systray.SpeedLimitType
+		want  time.Duration
+	}{
+		{"CPU 10%", systray.SpeedCPU10, 100 * time.Millisecond},
+		{"CPU 20%", systray.SpeedCPU20, 50 * time.Millisecond},
+		{"CPU 30%", systray.SpeedCPU30, 33 * time.Millisecond},
+		{"CPU 40%", systray.SpeedCPU40, 25 * time.Millisecond},
+		{"Default", systray.SpeedDefault, 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		h.click(t, "Runner Speed Limit", tt.item)
+		if s := h.nextSettings(t); s.SpeedLimit != tt.limit {
+			t.Fatalf("%s: reported limit %q, want %q", tt.item, s.SpeedLimit, tt.limit)
+		}
+		if got := h.checked(t, "Runner Speed Limit"); !slices.Equal(got, []string{tt.item}) {
+			t.Fatalf("%s: checked limits %v", tt.item, got)
+		}
+
+		// 限速期间采样不改变速度
+		h.manager.SetUsage(sample)
+		if got := h.manager.AnimationInterval(); got != tt.want {
+			t.Fatalf("%s: interval %v, want %v", tt.item, got, tt.want)
+		}
+	}
+
+	// 默认速度跟随采样值
+	h.manager.SetUsage(monitor.Sample{Source: monitor.SourceCPU, Unit: "%"})
+	if got := h.manager.AnimationInterval(); got != 200*time.Millisecond {
+		t.Fatalf("idle interval %v, want 200ms", got)
+	}
+}
+
+func TestCustomRunnerMenu(t *testing.T) {
+	rm := resource.NewResourceManager(testAssets())
+	err := rm.LoadRunners(fstest.MapFS{
+		"mascot/light/0.png": &fstest.MapFile{Data: []byte("mascot/light/0")},
+		"mascot/light/1.png": &fstest.MapFile{Data: []byte("mascot/light/1")},
+	})
+	if err != nil {
+		t.Fatalf("LoadRunners: %v", err)
+	}
+
+	// 配置中的角色已不存在时回退到默认角色
+	h := startManagerWith(t, rm, "light", systray.Settings{Runner: "removed"})
+	eventually(t, "cat icon", h.showing("cat/light/"))
+
+	var titles []string
+	for _, item := range h.backend.Item("Runner").Children() {
+		titles = append(titles, item.Title())
+	}
+	if want := []string{"Cat", "Parrot", "mascot"}; !slices.Equal(titles, want) {
+		t.Fatalf("runner menu %v, want %v", titles, want)
+	}
+
+	h.click(t, "Runner", "mascot")
+	if s := h.nextSettings(t); s.Runner != "mascot" {
+		t.Fatalf("reported runner %q, want mascot", s.Runner)
+	}
+	eventually(t, "mascot icon", h.showing("mascot/light/"))
+}
+
+func TestOverlayMenu(t *testing.T) {
+	// 叠加需要可以解码的帧
+	frame := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, frame); err != nil {
+		t.Fatal(err)
+	}
+	assets := fstest.MapFS{
+		"assets/cat/light/light_cat_0.png": {Data: buf.Bytes()},
+	}
+	h := startManagerWith(t, resource.NewResourceManager(assets), "light", systray.Settings{})
+
+	if got := h.checked(t, "Overlay"); !slices.Equal(got, []string{"Off"}) {
+		t.Fatalf("checked overlay %v, want [Off]", got)
+	}
+	eventually(t, "plain frame", func() bool { return bytes.Equal(h.backend.Icon(), buf.Bytes()) })
+
+	h.manager.SetUsage(monitor.Sample{Value: 42})
+	h.click(t, "Overlay", "Digits")
+	if s := h.nextSettings(t); s.Overlay != overlay.ModeDigits {
+		t.Fatalf("overlay %q, want digits", s.Overlay)
+	}
+	if got := h.checked(t, "Overlay"); !slices.Equal(got, []string{"Digits"}) {
+		t.Fatalf("checked overlay %v, want [Digits]", got)
+	}
+	eventually(t, "frame with digits", func() bool {
+		icon := h.backend.Icon()
+		return !bytes.Equal(icon, buf.Bytes()) && bytes.HasPrefix(icon, []byte("\x89PNG"))
+	})
+
+	h.click(t, "Overlay", "Off")
+	h.nextSettings(t)
+	eventually(t, "plain frame again", func() bool { return bytes.Equal(h.backend.Icon(), buf.Bytes()) })
+}
+
+func TestApplySettings(t *testing.T) {
+	h := startManager(t, "light", systray.Settings{})
+	eventually(t, "cat icon", h.showing("cat/light/"))
+
+	h.manager.ApplySettings(systray.Settings{
+		Runner:          resource.RunnerParrot,
+		Theme:           theme.DarkType,
+		SpeedLimit:      systray.SpeedCPU20,
+		Metric:          monitor.SourceCPU,
+		DriveExpression: "max(cpu, mem)",
+		Interval:        5 * time.Second,
+		Overlay:         overlay.ModeBar,
+	})
+
+	eventually(t, "dark parrot icon", h.showing("parrot/dark/"))
+	for menu, want := range map[string]string{
+		"Runner":             "Parrot",
+		"Theme":              "Dark",
+		"Runner Speed Limit": "CPU 20%",
+		"Metric":             "Expression: max(cpu, mem)",
+		"Update Interval":    "5s",
+		"Overlay":            "Bar",
+	} {
+		if got := h.checked(t, menu); !slices.Equal(got, []string{want}) {
+			t.Errorf("checked %s %v, want [%s]", menu, got, want)
+		}
+	}
+	if got := h.manager.AnimationInterval(); got != 50*time.Millisecond {
+		t.Errorf("interval %v, want 50ms", got)
+	}
+
+	// 外部修改不会触发回调，避免再次写回配置文件
+	select {
+	case s := <-h.settings:
+		t.Fatalf("unexpected settings change %+v", s)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// 清除表达式后隐藏表达式选项，不可用的角色被忽略
+	h.manager.ApplySettings(systray.Settings{Runner: "missing", Metric: monitor.SourceCPU})
+	if got := h.checked(t, "Metric"); !slices.Equal(got, []string{monitor.SourceLabel(monitor.SourceCPU)}) {
+		t.Errorf("checked metrics %v", got)
+	}
+	if item := h.backend.Item("Metric", "Expression: max(cpu, mem)"); item == nil || !item.Hidden() {
+		t.Error("expression item is still visible")
+	}
+	if s := h.manager.GetSettings(); s.Runner != resource.RunnerParrot || s.DriveExpression != "" {
+		t.Errorf("unexpected settings %+v", s)
+	}
+}
+
+func TestProfilesMenu(t *testing.T) {
+	h := startManager(t, "light", systray.Settings{Profile: "meeting", Profiles: []string{"build", "meeting"}})
+	selected := make(chan string, 1)
+	h.manager.SetOnProfileSelected(func(name string) { selected <- name })
+
+	if got := h.checked(t, "Profiles"); !slices.Equal(got, []string{"meeting"}) {
+		t.Fatalf("checked profiles %v, want [meeting]", got)
+	}
+
+	// 选择方案只调用回调，由ApplySettings应用方案中的设置
+	h.click(t, "Profiles", "build")
+	select {
+	case name := <-selected:
+		if name != "build" {
+			t.Fatalf("selected %q, want build", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("profile selection was not reported")
+	}
+	h.manager.ApplySettings(systray.Settings{Overlay: overlay.ModeDigits, Profile: "build", Profiles: []string{"build", "meeting", "battery"}})
+	if got := h.checked(t, "Profiles"); !slices.Equal(got, []string{"build"}) {
+		t.Errorf("checked profiles %v, want [build]", got)
+	}
+	if item := h.backend.Item("Profiles", "battery"); item == nil || item.Hidden() {
+		t.Error("added profile is not shown")
+	}
+
+	// 通过菜单修改单个设置后清除当前方案
+	h.click(t, "Overlay", "Bar")
+	if s := h.nextSettings(t); s.Overlay != overlay.ModeBar || s.Profile != "" {
+		t.Errorf("unexpected settings %+v", s)
+	}
+	if got := h.checked(t, "Profiles"); len(got) != 0 {
+		t.Errorf("checked profiles %v after changing overlay", got)
+	}
+
+	// 移除所有方案后隐藏菜单
+	h.manager.ApplySettings(systray.Settings{})
+	if item := h.backend.Item("Profiles"); item == nil || !item.Hidden() {
+		t.Error("profiles menu is still visible")
+	}
+	if item := h.backend.Item("Profiles", "build"); item == nil || !item.Hidden() {
+		t.Error("removed profile is still visible")
+	}
+}
+
+func TestProfilesMenuHiddenWithoutProfiles(t *testing.T) {
+	h := startManager(t, "light", systray.Settings{})
+	if item := h.backend.Item("Profiles"); item == nil || !item.Hidden() {
+		t.Error("profiles menu is visible without profiles")
+	}
+}
+
+func TestCoresMenu(t *testing.T) {
+	h := startManager(t, "light", systray.Settings{Metric: monitor.SourceCPUMax})
+
+	titles := func() []string {
+		var titles []string
+		for _, item := range h.backend.Item("CPU Cores").Children() {
+			if !item.Hidden() {
+				titles = append(titles, item.Title())
+			}
+		}
+		return titles
+	}
+
+	h.manager.SetUsage(monitor.Sample{Source: monitor.SourceCPUMax, Unit: "%", Value: 80, Raw: 80, Cores: []float64{10, 80, 30}})
+	if h.backend.Item("CPU Cores").Disabled() {
+		t.Error("cores menu is still disabled")
+	}
+	want := []string{"Core 0: 10.0%", "Core 1: 80.0% (hottest)", "Core 2: 30.0%"}
+	if got := titles(); !slices.Equal(got, want) {
+		t.Errorf("cores %v, want %v", got, want)
+	}
+
+	// 核心数减少时隐藏多余的菜单项，组合来源中的每核心数据同样显示
+	h.manager.SetUsage(monitor.Sample{Source: monitor.SourceExpression, Parts: []monitor.Sample{
+		{Source: monitor.SourceCPU, Cores: []float64{50, 20}},
+	}})
+	want = []string{"Core 0: 50.0% (hottest)", "Core 1: 20.0%"}
+	if got := titles(); !slices.Equal(got, want) {
+		t.Errorf("cores %v, want %v", got, want)
+	}
+}
+
+func TestSetUsageBeforeReady(t *testing.T) {
+	p := fakePlatform{theme: "light"}
+	b := systraytest.NewBackend()
+	m := systray.NewSystrayManager(b, p, resource.NewResourceManager(testAssets()), theme.NewManager(p), systray.Settings{})
+
+	// 监控在系统托盘就绪之前开始采样
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.SetUsage(monitor.Sample{Source: monitor.SourceCPU, Value: 50, Cores: []float64{40, 60}})
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		m.Start()
+		close(done)
+	}()
+	<-b.Ready()
+	eventually(t, "cores menu", func() bool {
+		item := b.Item("CPU Cores")
+		return item != nil && len(item.Children()) == 2
+	})
+	close(stop)
+	<-sampled
+	m.Quit()
+	<-done
+}
+
+func TestOnReady(t *testing.T) {
+	p := fakePlatform{theme: "light"}
+	b := systraytest.NewBackend()
+	m := systray.NewSystrayManager(b, p, resource.NewResourceManager(testAssets()), theme.NewManager(p), systray.Settings{})
+
+	// 回调时菜单已经创建完成，外部修改的设置会同步到菜单
+	ready := make(chan []string, 1)
+	m.SetOnReady(func() {
+		m.ApplySettings(systray.Settings{Overlay: overlay.ModeBar})
+		ready <- b.Item("Overlay").CheckedChildren()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		m.Start()
+		close(done)
+	}()
+	defer func() {
+		m.Quit()
+		<-done
+	}()
+
+	select {
+	case got := <-ready:
+		if want := []string{overlay.ModeBar.Label()}; !slices.Equal(got, want) {
+			t.Errorf("overlay menu %v, want %v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ready callback was not called")
+	}
+}
@@ -0,0 +1,300 @@
+// Package systraytest 提供记录调用的内存系统托盘后端，用于测试
+package systraytest
+
+import (
+	"sync"
+
+	"github.com/eatmoreapple/go-runcat/internal/systray"
+)
+
+// Backend 在内存中记录托盘调用的后端
+type Backend struct {
+	// 关闭后表示onReady已返回
+	ready chan struct{}
+	// 关闭后Run返回
+	quit     chan struct{}
+	quitOnce sync.Once
+
+	// 保护以下字段的互斥锁
+	mu sync.Mutex
+	// 设置过的图标，按时间顺序
+	icons [][]byte
+	// 设置过的提示文本，按时间顺序
+	tooltips []string
+	// 顶层菜单项，分隔线为nil
+	items []*MenuItem
+}
+
+// NewBackend 创建一个新的内存后端
+func NewBackend() *Backend {
+	return &Backend{
+		ready: make(chan struct{}),
+		quit:  make(chan struct{}),
+	}
+}
+
+// Run 调用onReady，然后阻塞直到Quit被调用
+func (b *Backend) Run(onReady, onExit func()) {
+	if onReady != nil {
+		onReady()
+	}
+	close(b.ready)
+	<-b.quit
+	if onExit != nil {
+		onExit()
+	}
+}
+
+// Ready 返回onReady返回后关闭的通道
+func (b *Backend) Ready() <-chan struct{} {
+	return b.ready
+}
+
+// Quit 使Run返回，可以多次调用
+func (b *Backend) Quit() {
+	b.quitOnce.Do(func() { close(b.quit) })
+}
+
+// SetIcon 记录托盘图标
+func (b *Backend) SetIcon(icon []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.icons = append(b.icons, icon)
+}
+
+// SetTooltip 记录托盘提示文本
+func (b *Backend) SetTooltip(tooltip string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tooltips = append(b.tooltips, tooltip)
+}
+
+// AddMenuItem 添加顶层菜单项
+func (b *Backend) AddMenuItem(title, tooltip string) systray.MenuItem {
+	return b.add(newMenuItem(title, tooltip, false, false))
+}
+
+// AddMenuItemCheckbox 添加带复选框的顶层菜单项
+func (b *Backend) AddMenuItemCheckbox(title, tooltip string, checked bool) systray.MenuItem {
+	return b.add(newMenuItem(title, tooltip, true, checked))
+}
+
+// AddSeparator 添加分隔线
+func (b *Backend) AddSeparator() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items = append(b.items, nil)
+}
+
+func (b *Backend) add(item *MenuItem) *MenuItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items = append(b.items, item)
+	return item
+}
+
+// Icons 返回设置过的所有图标
+func (b *Backend) Icons() [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]byte(nil), b.icons...)
+}
+
+// Icon 返回当前的托盘图标
+func (b *Backend) Icon() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.icons) == 0 {
+		return nil
+	}
+	return b.icons[len(b.icons)-1]
+}
+
+// Tooltip 返回当前的提示文本
+func (b *Backend) Tooltip() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.tooltips) == 0 {
+		return ""
+	}
+	return b.tooltips[len(b.tooltips)-1]
+}
+
+// Item 按标题路径查找菜单项，例如Item("Runner", "Cat")，找不到时返回nil
+func (b *Backend) Item(path ...string) *MenuItem {
+	b.mu.Lock()
+	items := append([]*MenuItem(nil), b.items...)
+	b.mu.Unlock()
+
+	var found *MenuItem
+	for i, title := range path {
+		found = nil
+		for _, item := range items {
+			if item != nil && item.Title() == title {
+				found = item
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		if i < len(path)-1 {
+			items = found.Children()
+		}
+	}
+	return found
+}
+
+// MenuItem 在内存中记录状态的菜单项
+type MenuItem struct {
+	// 点击通知通道
+	clicked chan struct{}
+	// 是否为复选框
+	checkbox bool
+
+	// 保护以下字段的互斥锁
+	mu       sync.Mutex
+	title    string
+	tooltip  string
+	checked  bool
+	disabled bool
+	hidden   bool
+	children []*MenuItem
+}
+
+func newMenuItem(title, tooltip string, checkbox, checked bool) *MenuItem {
+	return &MenuItem{
+		clicked:  make(chan struct{}),
+		checkbox: checkbox,
+		title:    title,
+		tooltip:  tooltip,
+		checked:  checked,
+	}
+}
+
+// AddSubMenuItem 添加子菜单项
+func (i *MenuItem) AddSubMenuItem(title, tooltip string) systray.MenuItem {
+	return i.add(newMenuItem(title, tooltip, false, false))
+}
+
+// AddSubMenuItemCheckbox 添加带复选框的子菜单项
+func (i *MenuItem) AddSubMenuItemCheckbox(title, tooltip string, checked bool) systray.MenuItem {
+	return i.add(newMenuItem(title, tooltip, true, checked))
+}
+
+func (i *MenuItem) add(child *MenuItem) *MenuItem {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.children = append(i.children, child)
+	return child
+}
+
+// Clicked 返回菜单项被点击时的通知通道
+func (i *MenuItem) Clicked() <-chan struct{} {
+	return i.clicked
+}
+
+// Click 模拟一次点击，阻塞直到事件被接收
+func (i *MenuItem) Click() {
+	i.clicked <- struct{}{}
+}
+
+// Check 勾选菜单项
+func (i *MenuItem) Check() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.checked = true
+}
+
+// Uncheck 取消勾选菜单项
+func (i *MenuItem) Uncheck() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.checked = false
+}
+
+// SetTitle 设置菜单项标题
+func (i *MenuItem) SetTitle(title string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.title = title
+}
+
+// Enable 启用菜单项
+func (i *MenuItem) Enable() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.disabled = false
+}
+
+// Disable 禁用菜单项
+func (i *MenuItem) Disable() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.disabled = true
+}
+
+// Show 显示菜单项
+func (i *MenuItem) Show() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.hidden = false
+}
+
+// Hide 隐藏菜单项
+func (i *MenuItem) Hide() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.hidden = true
+}
+
+// Title 返回菜单项标题
+func (i *MenuItem) Title() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.title
+}
+
+// Checked 检查菜单项是否被勾选
+func (i *MenuItem) Checked() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.checked
+}
+
+// Checkbox 检查菜单项是否为复选框
+func (i *MenuItem) Checkbox() bool {
+	return i.checkbox
+}
+
+// Disabled 检查菜单项是否被禁用
+func (i *MenuItem) Disabled() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.disabled
+}
+
+// Hidden 检查菜单项是否被隐藏
+func (i *MenuItem) Hidden() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.hidden
+}
+
+// Children 返回子菜单项
+func (i *MenuItem) Children() []*MenuItem {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]*MenuItem(nil), i.children...)
+}
+
+// CheckedChildren 返回子菜单中被勾选项的标题
+func (i *MenuItem) CheckedChildren() []string {
+	var titles []string
+	for _, child := range i.Children() {
+		if child.Checked() {
+			titles = append(titles, child.Title())
+		}
+	}
+	return titles
+}
@@ -18,6 +18,9 @@ const (
 	DarkType Type = "dark"
 )
 
+// Types 所有可选的主题设置
+var Types = []Type{AutoType, LightType, DarkType}
+
 // Manager 主题管理器
 type Manager struct {
 	// 当前主题设置
@@ -34,24 +37,26 @@ type Manager struct {
 
 // NewManager 创建一个新的主题管理器
 func NewManager(p platform.Platform) *Manager {
-	return &Manager{
+	m := &Manager{
 		currentTheme: AutoType,
 		platform:     p,
-		actualTheme:  LightType,
 	}
+	m.updateActualTheme()
+	return m
 }
 
 // SetTheme 设置主题
 func (m *Manager) SetTheme(theme Type) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if m.currentTheme == theme {
+		m.mu.Unlock()
 		return
 	}
-
 	m.currentTheme = theme
 	m.updateActualTheme()
+	m.mu.Unlock()
+
+	m.notifyThemeChanged()
 }
 
 // GetTheme 获取当前设置的主题
@@ -63,33 +68,37 @@ func (m *Manager) GetTheme() Type {
 
 // GetActualTheme 获取实际使用的主题
 func (m *Manager) GetActualTheme() Type {
-	// may cause deadlock here
-	//m.mu.RLock()
-	//defer m.mu.RUnlock()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.actualTheme
 }
 
 // SetOnThemeChanged 设置主题变化时的回调函数
+// 回调在不持有锁的情况下调用，可以在回调中访问Manager
 func (m *Manager) SetOnThemeChanged(callback func(theme Type)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.onThemeChanged = callback
 }
 
 // UpdateSystemTheme 更新系统主题
 func (m *Manager) UpdateSystemTheme() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if m.currentTheme == AutoType {
-		oldTheme := m.actualTheme
-		m.updateActualTheme()
+	if m.currentTheme != AutoType {
+		m.mu.Unlock()
+		return
+	}
+	oldTheme := m.actualTheme
+	m.updateActualTheme()
+	changed := oldTheme != m.actualTheme
+	m.mu.Unlock()
 
-		if oldTheme != m.actualTheme && m.onThemeChanged != nil {
-			m.onThemeChanged(m.actualTheme)
-		}
+	if changed {
+		m.notifyThemeChanged()
 	}
 }
 
-// 更新实际使用的主题
+// 更新实际使用的主题，调用时需持有锁
 func (m *Manager) updateActualTheme() {
 	if m.currentTheme == AutoType {
 		// 获取系统主题
@@ -102,8 +111,16 @@ func (m *Manager) updateActualTheme() {
 	} else {
 		m.actualTheme = m.currentTheme
 	}
+}
+
+// 通知主题变化
+func (m *Manager) notifyThemeChanged() {
+	m.mu.RLock()
+	callback, actualTheme := m.onThemeChanged, m.actualTheme
+	m.mu.RUnlock()
+
 	// 如果设置了回调函数，则调用
-	if m.onThemeChanged != nil {
-		m.onThemeChanged(m.actualTheme)
+	if callback != nil {
+		callback(actualTheme)
 	}
 }
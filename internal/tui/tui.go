@@ -0,0 +1,302 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/eatmoreapple/go-runcat/internal/animation"
+	"github.com/eatmoreapple/go-runcat/internal/monitor"
+)
+
+// Mode 终端渲染方式
+type Mode string
+
+const (
+	// ModeAuto 根据终端能力自动选择
+	ModeAuto Mode = "auto"
+	// ModeUnicode 使用半块字符和24位颜色
+	ModeUnicode Mode = "unicode"
+	// ModeASCII 使用纯ASCII字符
+	ModeASCII Mode = "ascii"
+	// ModeKitty 使用kitty图形协议
+	ModeKitty Mode = "kitty"
+)
+
+// ParseMode 解析渲染方式
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(s)); mode {
+	case ModeAuto, ModeUnicode, ModeASCII, ModeKitty:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown tui mode %q (allowed: auto, unicode, ascii, kitty)", s)
+	}
+}
+
+// DetectMode 根据环境变量判断终端支持的渲染方式
+func DetectMode() Mode {
+	term := os.Getenv("TERM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty":
+		return ModeKitty
+	case dumbTerminal() || os.Getenv("NO_COLOR") != "":
+		return ModeASCII
+	default:
+		return ModeUnicode
+	}
+}
+
+// 终端是否不支持光标移动和清屏等控制序列
+func dumbTerminal() bool {
+	return os.Getenv("TERM") == "dumb"
+}
+
+// Options 终端动画的选项
+type Options struct {
+	// 输出目标
+	Output io.Writer
+	// 动画帧
+	Frames []image.Image
+	// 渲染方式
+	Mode Mode
+	// 固定的指标值，用于限速；为0时跟随采样值
+	FixedUsage float64
+	// 每帧的相对时长和速度范围
+	Timing animation.Timing
+}
+
+// Terminal 在终端中渲染奔跑动画，作为动画引擎的输出
+type Terminal struct {
+	out        *bufio.Writer
+	fixedUsage float64
+	engine     *animation.Engine
+	frames     [][]byte
+	// 是否使用控制序列原地重绘，不支持时逐帧追加输出
+	escapes bool
+
+	// 保护以下字段的互斥锁
+	mu sync.Mutex
+	// 最新的采样
+	sample monitor.Sample
+	// 是否已收到采样
+	sampled bool
+	// 最近一次写入错误
+	err error
+}
+
+// New 创建终端动画，预先渲染所有帧
+func New(opts Options) (*Terminal, error) {
+	if len(opts.Frames) == 0 {
+		return nil, fmt.Errorf("tui: no frames to render")
+	}
+	mode := opts.Mode
+	if mode == "" || mode == ModeAuto {
+		mode = DetectMode()
+	}
+
+	escapes := !dumbTerminal()
+	frames := make([][]byte, len(opts.Frames))
+	for i, img := range opts.Frames {
+		frame, err := renderFrame(mode, img, escapes)
+		if err != nil {
+			return nil, fmt.Errorf("tui: render frame %d: %w", i, err)
+		}
+		frames[i] = []byte(frame)
+	}
+
+	t := &Terminal{
+		out:        bufio.NewWriter(opts.Output),
+		fixedUsage: opts.FixedUsage,
+		frames:     frames,
+		escapes:    escapes,
+	}
+	t.engine = animation.NewEngine(t)
+	t.engine.SetUsage(opts.FixedUsage)
+	t.engine.SetTiming(opts.Timing)
+	return t, nil
+}
+
+// SetUsage 设置驱动动画的监控指标采样
+func (t *Terminal) SetUsage(sample monitor.Sample) {
+	t.mu.Lock()
+	t.sample = sample
+	t.sampled = true
+	t.mu.Unlock()
+
+	if t.fixedUsage <= 0 {
+		t.engine.SetUsage(sample.Value)
+	}
+}
+
+// 当前状态行
+func (t *Terminal) status() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.sampled {
+		return "Sampling..."
+	}
+	return statusLine(t.sample)
+}
+
+// ShowFrame 绘制一帧和状态行，实现animation.Sink
+func (t *Terminal) ShowFrame(frame []byte) {
+	status := t.status()
+
+	if t.escapes {
+		// 回到左上角重绘，并清除每行剩余内容
+		_, _ = t.out.WriteString("\x1b[H")
+		_, _ = t.out.Write(frame)
+		_, _ = t.out.WriteString("\r\n" + status + "\x1b[K\r\n\x1b[J")
+	} else {
+		// 无法重绘时依次追加，每帧之后空一行
+		_, _ = t.out.Write(frame)
+		_, _ = t.out.WriteString("\n" + status + "\n\n")
+	}
+	if err := t.out.Flush(); err != nil {
+		t.mu.Lock()
+		t.err = err
+		t.mu.Unlock()
+	}
+}
+
+// Run 运行动画直到ctx取消，退出时恢复终端状态
+func (t *Terminal) Run(ctx context.Context) error {
+	// 隐藏光标并清屏
+	if t.escapes {
+		_, _ = t.out.WriteString("\x1b[?25l\x1b[2J")
+	}
+
+	t.engine.SetFrames(t.frames)
+	t.engine.Start(ctx)
+	<-ctx.Done()
+	t.engine.Stop()
+
+	if t.escapes {
+		_, _ = t.out.WriteString("\x1b[?25h\n")
+	}
+	_ = t.out.Flush()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// 状态行：采样描述和进度条
+func statusLine(sample monitor.Sample) string {
+	const width = 20
+	filled := int(sample.Value / 100 * width)
+	filled = max(0, min(width, filled))
+	return fmt.Sprintf("[%s%s] %s", strings.Repeat("#", filled), strings.Repeat(".", width-filled), sample)
+}
+
+// 按渲染方式渲染一帧，escapes为false时行尾不清除剩余内容
+func renderFrame(mode Mode, img image.Image, escapes bool) (string, error) {
+	lineEnd := "\x1b[K\r\n"
+	if !escapes {
+		lineEnd = "\n"
+	}
+	switch mode {
+	case ModeASCII:
+		return renderASCII(img, lineEnd), nil
+	case ModeKitty:
+		return renderKitty(img)
+	default:
+		return renderUnicode(img, lineEnd), nil
+	}
+}
+
+// renderUnicode 每个字符用上半块表示两行像素，前景色为上方像素，背景色为下方像素
+func renderUnicode(img image.Image, lineEnd string) string {
+	b := img.Bounds()
+	var sb strings.Builder
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			tr, tg, tb, ta := img.At(x, y).RGBA()
+			br, bg, bb, ba := uint32(0), uint32(0), uint32(0), uint32(0)
+			if y+1 < b.Max.Y {
+				br, bg, bb, ba = img.At(x, y+1).RGBA()
+			}
+			top, bottom := ta > 0x7fff, ba > 0x7fff
+
+			switch {
+			case top && bottom:
+				fmt.Fprintf(&sb, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", tr>>8, tg>>8, tb>>8, br>>8, bg>>8, bb>>8)
+			case top:
+				fmt.Fprintf(&sb, "\x1b[38;2;%d;%d;%dm▀", tr>>8, tg>>8, tb>>8)
+			case bottom:
+				fmt.Fprintf(&sb, "\x1b[38;2;%d;%d;%dm▄", br>>8, bg>>8, bb>>8)
+			default:
+				sb.WriteByte(' ')
+			}
+			sb.WriteString("\x1b[0m")
+		}
+		sb.WriteString(lineEnd)
+	}
+	return sb.String()
+}
+
+// renderASCII 每个字符表示两行像素，按覆盖程度选择字符
+func renderASCII(img image.Image, lineEnd string) string {
+	b := img.Bounds()
+	var sb strings.Builder
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_, _, _, ta := img.At(x, y).RGBA()
+			var ba uint32
+			if y+1 < b.Max.Y {
+				_, _, _, ba = img.At(x, y+1).RGBA()
+			}
+			top, bottom := ta > 0x7fff, ba > 0x7fff
+			switch {
+			case top && bottom:
+				sb.WriteByte('#')
+			case top:
+				sb.WriteByte('"')
+			case bottom:
+				sb.WriteByte('.')
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(lineEnd)
+	}
+	return sb.String()
+}
+
+// renderKitty 使用kitty图形协议显示PNG图像，复用同一个图像ID实现原地替换
+func renderKitty(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	// 每段负载最多4096字节
+	const chunkSize = 4096
+	var sb strings.Builder
+	for i := 0; i < len(payload); i += chunkSize {
+		end := min(i+chunkSize, len(payload))
+		more := 0
+		if end < len(payload) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&sb, "\x1b_Ga=T,f=100,i=1,q=2,C=1,m=%d;%s\x1b\\", more, payload[i:end])
+		} else {
+			fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, payload[i:end])
+		}
+	}
+	// 图像占据的行数按每行约16像素估算，为状态行留出空间
+	rows := (img.Bounds().Dy() + 15) / 16
+	sb.WriteString(strings.Repeat("\r\n", rows))
+	return sb.String(), nil
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestDetectMode(t *testing.T) {
+	tests := []struct {
+		term    string
+		kitty   string
+		noColor string
+		want    Mode
+	}{
+		{term: "xterm-256color", want: ModeUnicode},
+		{term: "xterm-kitty", want: ModeKitty},
+		{term: "xterm-256color", kitty: "1", want: ModeKitty},
+		{term: "dumb", want: ModeASCII},
+		{term: "xterm-256color", noColor: "1", want: ModeASCII},
+	}
+	for _, tt := range tests {
+		t.Setenv("TERM", tt.term)
+		t.Setenv("KITTY_WINDOW_ID", tt.kitty)
+		t.Setenv("NO_COLOR", tt.noColor)
+		if got := DetectMode(); got != tt.want {
+			t.Errorf("DetectMode() with TERM=%q KITTY_WINDOW_ID=%q NO_COLOR=%q = %q, want %q", tt.term, tt.kitty, tt.noColor, got, tt.want)
+		}
+	}
+}
+
+// 3x3的测试帧，覆盖上下像素的所有组合以及没有下方像素的最后一行
+func testFrame() image.Image {
+	red := color.NRGBA{R: 255, A: 255}
+	green := color.NRGBA{G: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	img.Set(0, 0, red)
+	img.Set(2, 0, blue)
+	img.Set(0, 1, red)
+	img.Set(1, 1, green)
+	img.Set(1, 2, white)
+	return img
+}
+
+func TestRunOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		term string
+		mode Mode
+		want string
+	}{
+		{
+			name: "ascii on a dumb terminal",
+			term: "dumb",
+			mode: ModeASCII,
+			want: "#.\"\n" +
+				" \" \n" +
+				"\nSampling...\n\n",
+		},
+		{
+			name: "ascii",
+			term: "xterm-256color",
+			mode: ModeASCII,
+			want: "\x1b[?25l\x1b[2J" +
+				"\x1b[H" +
+				"#.\"\x1b[K\r\n" +
+				" \" \x1b[K\r\n" +
+				"\r\nSampling...\x1b[K\r\n\x1b[J" +
+				"\x1b[?25h\n",
+		},
+		{
+			name: "unicode",
+			term: "xterm-256color",
+			mode: ModeUnicode,
+			want: "\x1b[?25l\x1b[2J" +
+				"\x1b[H" +
+				"\x1b[38;2;255;0;0m\x1b[48;2;255;0;0m▀\x1b[0m" +
+				"\x1b[38;2;0;255;0m▄\x1b[0m" +
+				"\x1b[38;2;0;0;255m▀\x1b[0m\x1b[K\r\n" +
+				" \x1b[0m" +
+				"\x1b[38;2;255;255;255m▀\x1b[0m" +
+				" \x1b[0m\x1b[K\r\n" +
+				"\r\nSampling...\x1b[K\r\n\x1b[J" +
+				"\x1b[?25h\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TERM", tt.term)
+
+			var out bytes.Buffer
+			terminal, err := New(Options{Output: &out, Frames: []image.Image{testFrame()}, Mode: tt.mode})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			// 已取消的ctx只输出SetFrames时的第一帧
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := terminal.Run(ctx); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("output =\n%q\nwant\n%q", got, tt.want)
+			}
+			if tt.term == "dumb" && strings.Contains(out.String(), "\x1b") {
+				t.Error("output for a dumb terminal contains escape sequences")
+			}
+		})
+	}
+}